@@ -3,6 +3,8 @@ package list
 import (
 	"fmt"
 	"io"
+	"reflect"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
@@ -22,8 +24,29 @@ type DefaultItemStyles struct {
 	// The dimmed state, for when the filter input is initially activated.
 	DimmedTitle lipgloss.Style
 
+	// The state of pinned and recent items which aren't selected, see
+	// Model.PinItem and Model.SetShowRecents. Pinned items are also marked
+	// with a star.
+	PinnedTitle lipgloss.Style
+
+	// The state of the item under the mouse when it isn't selected, see
+	// Model.IsHovered.
+	HoverTitle lipgloss.Style
+
 	// Characters matching the current filter, if any.
 	FilterMatch lipgloss.Style
+
+	// The item's index, when DefaultDelegate.ShowIndex is set.
+	ItemIndex lipgloss.Style
+
+	// Alternating row styles, when DefaultDelegate.ZebraStripe is set. These
+	// are applied on top of the normal and dimmed states, but not the selected
+	// state.
+	EvenRow lipgloss.Style
+	OddRow  lipgloss.Style
+
+	// The line rendered between items, when DefaultDelegate.Separator is set.
+	Separator lipgloss.Style
 }
 
 // NewDefaultItemStyles returns style definitions for a default item. See
@@ -43,8 +66,71 @@ func NewDefaultItemStyles() (s DefaultItemStyles) {
 		Foreground(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"}).
 		Padding(0, 0, 0, 2)
 
+	s.PinnedTitle = lipgloss.NewStyle().
+		Foreground(lipgloss.AdaptiveColor{Light: "#B8860B", Dark: "#F2C94C"}).
+		Padding(0, 0, 0, 2)
+
+	s.HoverTitle = lipgloss.NewStyle().
+		Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"}).
+		Background(lipgloss.AdaptiveColor{Light: "#EDEDED", Dark: "#2A2A2A"}).
+		Padding(0, 0, 0, 2)
+
 	s.FilterMatch = lipgloss.NewStyle().Underline(true)
 
+	s.ItemIndex = lipgloss.NewStyle().
+		Foreground(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"})
+
+	s.EvenRow = lipgloss.NewStyle()
+
+	s.OddRow = lipgloss.NewStyle().
+		Background(lipgloss.AdaptiveColor{Light: "#F2F2F2", Dark: "#1E1E1E"})
+
+	s.Separator = lipgloss.NewStyle().
+		Foreground(lipgloss.AdaptiveColor{Light: "#DDDADA", Dark: "#3C3C3C"})
+
+	return s
+}
+
+// Direction describes the direction in which item text is laid out.
+type Direction int
+
+// Possible text directions.
+const (
+	LeftToRight Direction = iota // text is left-aligned and truncated on the right
+	RightToLeft                  // text is right-aligned and truncated on the left
+)
+
+// LoadingItem is an optional interface for items which load data on their own,
+// such as a preview fetched in the background. DefaultDelegate renders the
+// list's spinner next to items which are loading. See Model.StartItemSpinner.
+type LoadingItem interface {
+	Item
+	IsLoading() bool
+}
+
+// StyledItem is an optional interface for items which should be rendered with
+// their own styles by DefaultDelegate, regardless of the delegate's styles.
+// Fields left unset in the returned styles fall back to the delegate's. If nil
+// is returned the delegate's styles are used.
+//
+// The fallbacks are resolved once per returned pointer, the first time an
+// item using it is rendered, so return a new pointer rather than changing the
+// styles in place. See DefaultDelegate.SetStyles.
+type StyledItem interface {
+	Item
+	Styles() *DefaultItemStyles
+}
+
+// withFallback returns a copy of the styles where every field that hasn't been
+// set is taken from the fallback styles.
+func (s DefaultItemStyles) withFallback(fallback DefaultItemStyles) DefaultItemStyles {
+	sv := reflect.ValueOf(&s).Elem()
+	fv := reflect.ValueOf(fallback)
+	for i := 0; i < sv.NumField(); i++ {
+		if sv.Field(i).IsZero() {
+			sv.Field(i).Set(fv.Field(i))
+		}
+	}
 	return s
 }
 
@@ -63,22 +149,63 @@ type DefaultItem interface {
 //
 // Settings ShortHelpFunc and FullHelpFunc is optional. They can be set to
 // include items in the list's default short and full help menus.
+//
+// Setting ShowIndex prefixes each item with its 1-based position in the list
+// of available items. Setting ZebraStripe styles rows with alternating
+// backgrounds, filling the full width of the list. Setting FullWidthSelection
+// fills the full width of the selected row, so a background color set on
+// SelectedTitle covers the entire line.
+//
+// Setting Separator draws a line between items, made by repeating the string
+// across the width of the list, in place of the first blank spacing line.
 type DefaultDelegate struct {
 	Styles        DefaultItemStyles
 	UpdateFunc    func(tea.Msg, *Model) tea.Cmd
 	ShortHelpFunc func() []key.Binding
 	FullHelpFunc  func() [][]key.Binding
-	height        int
-	spacing       int
+
+	ShowIndex          bool
+	ZebraStripe        bool
+	FullWidthSelection bool
+	Separator          string
+
+	height    int
+	spacing   int
+	direction Direction
+
+	// itemStyles holds the styles of StyledItems with the fallbacks to
+	// Styles resolved, keyed by the styles the items returned.
+	itemStyles map[*DefaultItemStyles]DefaultItemStyles
 }
 
 // NewDefaultDelegate creates a new delegate with default styles.
 func NewDefaultDelegate() DefaultDelegate {
 	return DefaultDelegate{
-		Styles:  NewDefaultItemStyles(),
-		height:  1,
-		spacing: 1,
+		Styles:     NewDefaultItemStyles(),
+		height:     1,
+		spacing:    1,
+		itemStyles: make(map[*DefaultItemStyles]DefaultItemStyles),
+	}
+}
+
+// SetStyles sets the delegate's styles. Unlike setting Styles directly, it
+// also resolves the styles of StyledItems against the new styles.
+func (d *DefaultDelegate) SetStyles(s DefaultItemStyles) {
+	d.Styles = s
+	d.itemStyles = make(map[*DefaultItemStyles]DefaultItemStyles)
+}
+
+// resolveItemStyles returns the item's styles with unset fields taken from
+// the delegate's styles, resolving them only the first time they're seen.
+func (d DefaultDelegate) resolveItemStyles(itemStyles *DefaultItemStyles) DefaultItemStyles {
+	if styles, ok := d.itemStyles[itemStyles]; ok {
+		return styles
 	}
+	styles := itemStyles.withFallback(d.Styles)
+	if d.itemStyles != nil {
+		d.itemStyles[itemStyles] = styles
+	}
+	return styles
 }
 
 // Height returns the delegate's preferred height.
@@ -91,11 +218,37 @@ func (d *DefaultDelegate) SetSpacing(i int) {
 	d.spacing = i
 }
 
-// Spacing returns the delegate's spacing.
+// Spacing returns the delegate's spacing. When a separator is set the spacing
+// is at least one line, to make room for it.
 func (d DefaultDelegate) Spacing() int {
+	if d.Separator != "" {
+		return max(1, d.spacing)
+	}
 	return d.spacing
 }
 
+// RenderSeparator renders the separator line drawn between items. It
+// satisfies the SeparatorDelegate interface.
+func (d DefaultDelegate) RenderSeparator(w io.Writer, m Model) {
+	sepWidth := lipgloss.Width(d.Separator)
+	if sepWidth == 0 || m.width < sepWidth {
+		return
+	}
+	fmt.Fprint(w, d.Styles.Separator.Render(strings.Repeat(d.Separator, m.width/sepWidth)))
+}
+
+// SetDirection sets the direction in which items are laid out. When set to
+// RightToLeft, items are right-aligned, truncated from the left and styles
+// such as padding and borders are mirrored.
+func (d *DefaultDelegate) SetDirection(v Direction) {
+	d.direction = v
+}
+
+// Direction returns the direction in which items are laid out.
+func (d DefaultDelegate) Direction() Direction {
+	return d.direction
+}
+
 // Update checks whether the delegate's UpdateFunc is set and calls it.
 func (d DefaultDelegate) Update(msg tea.Msg, m *Model) tea.Cmd {
 	if d.UpdateFunc == nil {
@@ -109,57 +262,148 @@ func (d DefaultDelegate) Render(w io.Writer, m Model, index int, item Item) {
 	var (
 		title        string
 		matchedRunes []int
-		s            = &d.Styles
+		styles       = d.Styles
+		s            = &styles
+		rtl          = d.direction == RightToLeft
 	)
 
-	if i, ok := item.(DefaultItem); ok {
+	// Wrappers such as CachedItem don't implement the optional interfaces
+	// of the items they wrap.
+	unwrapped := unwrapItem(item)
+	switch i := unwrapped.(type) {
+	case GroupItem:
+		title = m.groupTitle(index, i)
+	case DefaultItem:
 		title = i.Title()
-	} else {
+	default:
 		return
 	}
 
+	if i, ok := unwrapped.(StyledItem); ok {
+		if itemStyles := i.Styles(); itemStyles != nil {
+			styles = d.resolveItemStyles(itemStyles)
+		}
+	}
+
 	if m.width <= 0 {
 		// short-circuit
 		return
 	}
 
-	// Prevent text from exceeding list width
-	textwidth := uint(
-		m.width - s.NormalTitle.GetPaddingLeft() - s.NormalTitle.GetPaddingRight(),
-	)
-	title = truncate.StringWithTail(title, textwidth, ellipsis)
+	if rtl {
+		s.NormalTitle = mirrorStyle(s.NormalTitle)
+		s.SelectedTitle = mirrorStyle(s.SelectedTitle)
+		s.DimmedTitle = mirrorStyle(s.DimmedTitle)
+		s.PinnedTitle = mirrorStyle(s.PinnedTitle)
+		s.HoverTitle = mirrorStyle(s.HoverTitle)
+	}
 
 	// Conditions
 	var (
-		isSelected  = index == m.Index()
+		isSelected  = m.IsSelected(index)
 		emptyFilter = m.FilterState() == Filtering && m.FilterValue() == ""
 		isFiltered  = m.FilterState() == Filtering ||
 			m.FilterState() == FilterApplied
+		highlight = isFiltered || m.highlightTerm() != ""
 	)
 
-	if isFiltered && index < len(m.filteredItems) {
+	if highlight {
 		// Get indices of matched characters
-		matchedRunes = m.MatchesForItem(index)
+		matchedRunes = m.HighlightsForItem(index)
+	}
+
+	// Index numbers, right-aligned to the widest index in the list
+	var before, after string
+	if d.ShowIndex {
+		digits := len(fmt.Sprint(len(m.AvailableItems())))
+		num := s.ItemIndex.Inline(true).Render(fmt.Sprintf("%*d", digits, index+1))
+		if rtl {
+			after = " " + num
+		} else {
+			before = num + " "
+		}
+	}
+
+	// Star leading pinned items
+	pinned := m.IsPinned(item)
+	if pinned {
+		if rtl {
+			after = " " + pinGlyph + after
+		} else {
+			before += pinGlyph + " "
+		}
 	}
 
-	if emptyFilter {
-		title = s.DimmedTitle.Render(title)
-	} else if isSelected && m.FilterState() != Filtering {
-		if isFiltered {
-			// Highlight matches
-			unmatched := s.SelectedTitle.Inline(true)
-			matched := unmatched.Copy().Inherit(s.FilterMatch)
-			title = lipgloss.StyleRunes(title, matchedRunes, matched, unmatched)
+	// Spinner trailing items which are loading
+	if i, ok := unwrapped.(LoadingItem); ok && i.IsLoading() {
+		if rtl {
+			before = m.spinnerView() + " " + before
+		} else {
+			after += " " + m.spinnerView()
 		}
-		title = s.SelectedTitle.Render(title)
+	}
+
+	// Prevent text from exceeding list width
+	textwidth := uint(max(0,
+		m.width-s.NormalTitle.GetPaddingLeft()-s.NormalTitle.GetPaddingRight()-
+			lipgloss.Width(before+after),
+	))
+	if rtl {
+		title, matchedRunes = truncateLeft(title, textwidth, ellipsis, matchedRunes)
 	} else {
-		if isFiltered {
-			// Highlight matches
-			unmatched := s.NormalTitle.Inline(true)
-			matched := unmatched.Copy().Inherit(s.FilterMatch)
-			title = lipgloss.StyleRunes(title, matchedRunes, matched, unmatched)
+		title = truncate.StringWithTail(title, textwidth, ellipsis)
+	}
+
+	var style lipgloss.Style
+	switch {
+	case emptyFilter:
+		style = s.DimmedTitle
+	case isSelected && m.FilterState() != Filtering:
+		style = s.SelectedTitle
+	case m.IsHovered(index):
+		style = s.HoverTitle
+	case pinned || m.IsRecent(index):
+		style = s.PinnedTitle
+	default:
+		style = s.NormalTitle
+	}
+
+	// Rows which have a background fill the full width of the list
+	var (
+		showSelected = isSelected && m.FilterState() != Filtering
+		hovered      = !emptyFilter && !showSelected && m.IsHovered(index)
+		fullWidth    = d.FullWidthSelection && showSelected || hovered
+	)
+	if d.ZebraStripe && !showSelected {
+		stripe := s.EvenRow
+		if index%2 == 1 {
+			stripe = s.OddRow
+		}
+		style = style.Copy().Inherit(stripe)
+		fullWidth = true
+	}
+	if fullWidth {
+		style = style.Copy().Width(
+			m.width - style.GetBorderLeftSize() - style.GetBorderRightSize(),
+		)
+		if rtl {
+			style = style.Align(lipgloss.Right)
 		}
-		title = s.NormalTitle.Render(title)
+	}
+
+	if highlight && !emptyFilter {
+		// Highlight matches
+		unmatched := style.Inline(true)
+		matched := unmatched.Copy().Inherit(s.FilterMatch)
+		title = HighlightMatches(title, matchedRunes, matched, unmatched)
+	} else if before != "" || after != "" {
+		// Style the title on its own so it isn't affected by the index's style
+		title = style.Inline(true).Render(title)
+	}
+	title = style.Render(before + title + after)
+
+	if rtl {
+		title = lipgloss.PlaceHorizontal(m.width, lipgloss.Right, title)
 	}
 
 	fmt.Fprintf(w, "%s", title)
@@ -180,3 +424,68 @@ func (d DefaultDelegate) FullHelp() [][]key.Binding {
 	}
 	return nil
 }
+
+// HighlightMatches styles the runes of text at the given indexes with matched
+// and the other runes with unmatched, the way DefaultDelegate highlights the
+// matches of the filter. Indexes are rune positions, such as those returned
+// by Model.HighlightsForItem, so custom delegates can highlight matches the
+// same way. Both styles are rendered inline.
+func HighlightMatches(text string, matches []int, matched, unmatched lipgloss.Style) string {
+	return lipgloss.StyleRunes(text, matches, matched.Inline(true), unmatched.Inline(true))
+}
+
+// mirrorStyle swaps the left and right padding, margins and borders of a
+// style, for rendering right-to-left text.
+func mirrorStyle(s lipgloss.Style) lipgloss.Style {
+	pt, pr, pb, pl := s.GetPadding()
+	mt, mr, mb, ml := s.GetMargin()
+	b, bt, br, bb, bl := s.GetBorder()
+
+	b.Left, b.Right = b.Right, b.Left
+	b.TopLeft, b.TopRight = b.TopRight, b.TopLeft
+	b.BottomLeft, b.BottomRight = b.BottomRight, b.BottomLeft
+
+	return s.Copy().
+		Padding(pt, pl, pb, pr).
+		Margin(mt, ml, mb, mr).
+		Border(b, bt, bl, bb, br).
+		BorderLeftForeground(s.GetBorderRightForeground()).
+		BorderRightForeground(s.GetBorderLeftForeground()).
+		BorderLeftBackground(s.GetBorderRightBackground()).
+		BorderRightBackground(s.GetBorderLeftBackground())
+}
+
+// truncateLeft truncates a string from the left so that it fits within the
+// given width, prepending tail if anything was removed. The rune indices in
+// matches are shifted to line up with the truncated string.
+func truncateLeft(s string, width uint, tail string, matches []int) (string, []int) {
+	if uint(lipgloss.Width(s)) <= width {
+		return s, matches
+	}
+
+	var (
+		runes     = []rune(s)
+		tailWidth = lipgloss.Width(tail)
+		avail     = int(width) - tailWidth
+		start     = len(runes)
+	)
+
+	for start > 0 {
+		w := lipgloss.Width(string(runes[start-1]))
+		if w > avail {
+			break
+		}
+		avail -= w
+		start--
+	}
+
+	offset := len([]rune(tail)) - start
+	shifted := make([]int, 0, len(matches))
+	for _, i := range matches {
+		if i >= start {
+			shifted = append(shifted, i+offset)
+		}
+	}
+
+	return tail + string(runes[start:]), shifted
+}
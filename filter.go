@@ -0,0 +1,196 @@
+package list
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/sahilm/fuzzy"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MultiTermFilter uses the sahilm/fuzzy to filter through the list, splitting
+// the term on whitespace and requiring every sub-term to match a target, so
+// "foo bar" matches "bar of foo". Matched indexes of all the sub-terms are
+// merged for highlighting. Results are sorted by their combined score.
+func MultiTermFilter(term string, targets []string) []Rank {
+	terms := strings.Fields(term)
+	if len(terms) == 0 {
+		return nil
+	}
+	return matchAllTerms(terms, targets, make([]bool, len(targets)))
+}
+
+// ExclusionFilter works like MultiTermFilter, but sub-terms starting with "!"
+// exclude targets instead: "error !debug" matches targets fuzzy matching
+// "error" which don't contain "debug". Exclusions are case-insensitive
+// substring matches, as fuzzy matching would exclude far too much. Only the
+// included sub-terms are highlighted. A lone "!" is ignored.
+func ExclusionFilter(term string, targets []string) []Rank {
+	var include, exclude []string
+	for _, t := range strings.Fields(term) {
+		switch {
+		case t == "!":
+			continue
+		case strings.HasPrefix(t, "!"):
+			exclude = append(exclude, strings.ToLower(t[1:]))
+		default:
+			include = append(include, t)
+		}
+	}
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil
+	}
+
+	excluded := make([]bool, len(targets))
+	for i, target := range targets {
+		target = strings.ToLower(target)
+		for _, e := range exclude {
+			if strings.Contains(target, e) {
+				excluded[i] = true
+				break
+			}
+		}
+	}
+	return matchAllTerms(include, targets, excluded)
+}
+
+// matchAllTerms returns ranks for the targets matched by every term, skipping
+// targets which are excluded, sorted by their combined score.
+func matchAllTerms(terms []string, targets []string, excluded []bool) []Rank {
+	type match struct {
+		terms   int
+		score   int
+		indexes []int
+	}
+	matches := make(map[int]*match)
+
+	for _, t := range terms {
+		for _, r := range fuzzy.FindNoSort(t, targets) {
+			if excluded[r.Index] {
+				continue
+			}
+			m, ok := matches[r.Index]
+			if !ok {
+				m = &match{}
+				matches[r.Index] = m
+			}
+			m.terms++
+			m.score += r.Score
+			m.indexes = append(m.indexes, r.MatchedIndexes...)
+		}
+	}
+
+	// Without any terms, every target which isn't excluded matches.
+	var ranks []Rank
+	scores := make(map[int]int)
+	for i := range targets {
+		if excluded[i] {
+			continue
+		}
+		m, ok := matches[i]
+		if !ok {
+			if len(terms) > 0 {
+				continue
+			}
+			m = &match{}
+		}
+		if m.terms < len(terms) {
+			continue
+		}
+		ranks = append(ranks, Rank{
+			Index:          i,
+			MatchedIndexes: mergeIndexes(m.indexes),
+		})
+		scores[i] = m.score
+	}
+	sort.SliceStable(ranks, func(i, j int) bool {
+		return scores[ranks[i].Index] > scores[ranks[j].Index]
+	})
+	return ranks
+}
+
+// mergeIndexes sorts indexes and removes duplicates.
+func mergeIndexes(indexes []int) []int {
+	sort.Ints(indexes)
+	result := indexes[:0]
+	for _, index := range indexes {
+		if len(result) > 0 && index == result[len(result)-1] {
+			continue
+		}
+		result = append(result, index)
+	}
+	return result
+}
+
+// AccentInsensitiveFilter uses the sahilm/fuzzy to filter through the list,
+// ignoring diacritics in both the term and the targets, so "cafe" matches
+// "café". Matched indexes refer to runes in the original targets, so
+// highlighting lines up with the text as it's displayed.
+func AccentInsensitiveFilter(term string, targets []string) []Rank {
+	folded := make([]string, len(targets))
+	mappings := make([][]int, len(targets))
+	for i, t := range targets {
+		folded[i], mappings[i] = foldAccents(t)
+	}
+
+	foldedTerm, _ := foldAccents(term)
+	ranks := DefaultFilter(foldedTerm, folded)
+	for i, r := range ranks {
+		ranks[i].MatchedIndexes = unfoldMatches(
+			[]rune(targets[r.Index]),
+			mappings[r.Index],
+			r.MatchedIndexes,
+		)
+	}
+	return ranks
+}
+
+// foldAccents removes diacritics from a string by decomposing it and dropping
+// combining marks. It returns the folded string along with a mapping from
+// each rune in the folded string to the index of the rune it came from in the
+// original string.
+func foldAccents(s string) (string, []int) {
+	var (
+		folded  []rune
+		mapping []int
+	)
+	for i, r := range []rune(s) {
+		for _, d := range norm.NFD.String(string(r)) {
+			if unicode.Is(unicode.Mn, d) {
+				continue
+			}
+			folded = append(folded, d)
+			mapping = append(mapping, i)
+		}
+	}
+	return string(folded), mapping
+}
+
+// unfoldMatches translates rune indexes in a folded string back to rune
+// indexes in the original string. Combining marks following a matched rune in
+// the original string are included so they're styled along with the rune
+// they modify.
+func unfoldMatches(original []rune, mapping []int, matches []int) []int {
+	result := make([]int, 0, len(matches))
+	seen := make(map[int]bool, len(matches))
+	for _, m := range matches {
+		if m < 0 || m >= len(mapping) {
+			continue
+		}
+		i := mapping[m]
+		if seen[i] {
+			continue
+		}
+		seen[i] = true
+		result = append(result, i)
+
+		for j := i + 1; j < len(original) && unicode.Is(unicode.Mn, original[j]); j++ {
+			if !seen[j] {
+				seen[j] = true
+				result = append(result, j)
+			}
+		}
+	}
+	return result
+}
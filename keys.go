@@ -13,6 +13,54 @@ type KeyMap struct {
 	Filter      key.Binding
 	ClearFilter key.Binding
 
+	// Keybindings used for moving the selection by the number of items in
+	// view.
+	PageUp   key.Binding
+	PageDown key.Binding
+
+	// Multi-key sequence for going to the start of the list. Each key of the
+	// binding is a sequence of keys separated by spaces, such as "g g".
+	GoToStartSequence key.Binding
+
+	// Keybindings used for going back and forth through previously selected
+	// items.
+	JumpBack    key.Binding
+	JumpForward key.Binding
+
+	// Keybinding used for activating the selected item, see
+	// Model.OnActivate. By default it shares enter with ToggleCollapse, which
+	// wins on group items while unfiltered.
+	Activate key.Binding
+
+	// Keybinding used for collapsing and expanding the group headed by the
+	// selected item, see GroupItem. It takes precedence over Activate on
+	// group items while unfiltered. While a filter is applied groups can't be
+	// collapsed, so Activate fires on them instead.
+	ToggleCollapse key.Binding
+
+	// Keybindings used for expanding and collapsing every group at once.
+	ExpandAll   key.Binding
+	CollapseAll key.Binding
+
+	// Keybindings used for searching, which moves the selection to matching
+	// items without hiding the others, and for cycling through the matches of
+	// the search, Model.PersistentHighlight or the applied filter.
+	Search    key.Binding
+	NextMatch key.Binding
+	PrevMatch key.Binding
+
+	// Keybinding used for removing the last filter term pushed with
+	// Model.PushFilter.
+	PopFilter key.Binding
+
+	// Keybinding used for hiding the applied filter and showing it again,
+	// see Model.ToggleFilterApplied.
+	ToggleFilterApplied key.Binding
+
+	// Keybinding used for copying the applied filter term to the clipboard.
+	// It's disabled by default and requires Model.Clipboard to be set.
+	CopyFilter key.Binding
+
 	// Keybindings used for moving an item in the list.
 	MoveUp   key.Binding
 	MoveDown key.Binding
@@ -21,6 +69,17 @@ type KeyMap struct {
 	CancelWhileFiltering key.Binding
 	AcceptWhileFiltering key.Binding
 
+	// Keybinding used for emptying the filter input while staying in the
+	// filtering state. It's matched before the filter input's own keys, so
+	// by default ctrl+u clears the whole filter rather than only the text
+	// before the cursor.
+	ClearFilterText key.Binding
+
+	// Keybinding used for accepting the completion shown after the filter
+	// input, see Model.SetShowFilterCompletion. It takes precedence over
+	// AcceptWhileFiltering while there's a completion.
+	AcceptCompletion key.Binding
+
 	// Help toggle keybindings.
 	ShowFullHelp  key.Binding
 	CloseFullHelp key.Binding
@@ -30,6 +89,11 @@ type KeyMap struct {
 
 	// The quit-no-matter-what keybinding. This will be caught when filtering.
 	ForceQuit key.Binding
+
+	// Keybindings used for answering the prompt shown before quitting when
+	// Model.ConfirmQuit is set.
+	ConfirmQuit key.Binding
+	CancelQuit  key.Binding
 }
 
 // DefaultKeyMap returns a default set of keybindings.
@@ -52,6 +116,28 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("end", "G"),
 			key.WithHelp("G/end", "go to end"),
 		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup"),
+			key.WithHelp("pgup", "page up"),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown"),
+			key.WithHelp("pgdown", "page down"),
+		),
+		GoToStartSequence: key.NewBinding(
+			key.WithKeys("g g"),
+			key.WithHelp("gg", "go to start"),
+		),
+		JumpBack: key.NewBinding(
+			key.WithKeys("ctrl+o"),
+			key.WithHelp("ctrl+o", "jump back"),
+		),
+		// Terminals send ctrl+i as tab. Tab also accepts completions, but
+		// only while filtering, when JumpForward is disabled.
+		JumpForward: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "jump forward"),
+		),
 		Filter: key.NewBinding(
 			key.WithKeys("/"),
 			key.WithHelp("/", "filter"),
@@ -61,6 +147,57 @@ func DefaultKeyMap() KeyMap {
 			key.WithHelp("esc", "clear filter"),
 		),
 
+		// Activating.
+		Activate: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "choose"),
+		),
+
+		// Grouping.
+		ToggleCollapse: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "toggle group"),
+		),
+		ExpandAll: key.NewBinding(
+			key.WithKeys("+"),
+			key.WithHelp("+", "expand all"),
+		),
+		CollapseAll: key.NewBinding(
+			key.WithKeys("-"),
+			key.WithHelp("-", "collapse all"),
+		),
+
+		// Searching.
+		Search: key.NewBinding(
+			key.WithKeys("ctrl+f"),
+			key.WithHelp("ctrl+f", "search"),
+		),
+		NextMatch: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "next match"),
+		),
+		PrevMatch: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "prev match"),
+		),
+
+		// Chained filtering.
+		PopFilter: key.NewBinding(
+			key.WithKeys("backspace"),
+			key.WithHelp("backspace", "pop filter"),
+		),
+		ToggleFilterApplied: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "toggle filter"),
+		),
+
+		// Copying.
+		CopyFilter: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy filter"),
+			key.WithDisabled(),
+		),
+
 		// Moving
 		MoveUp: key.NewBinding(
 			key.WithKeys("K"),
@@ -88,6 +225,14 @@ func DefaultKeyMap() KeyMap {
 			),
 			key.WithHelp("enter", "apply filter"),
 		),
+		ClearFilterText: key.NewBinding(
+			key.WithKeys("ctrl+u"),
+			key.WithHelp("ctrl+u", "clear"),
+		),
+		AcceptCompletion: key.NewBinding(
+			key.WithKeys("right", "tab"),
+			key.WithHelp("→/tab", "complete"),
+		),
 
 		// Toggle help.
 		ShowFullHelp: key.NewBinding(
@@ -105,5 +250,56 @@ func DefaultKeyMap() KeyMap {
 			key.WithHelp("q", "quit"),
 		),
 		ForceQuit: key.NewBinding(key.WithKeys("ctrl+c")),
+
+		// Confirming quitting.
+		ConfirmQuit: key.NewBinding(
+			key.WithKeys("y", "Y"),
+			key.WithHelp("y", "quit"),
+		),
+		CancelQuit: key.NewBinding(
+			key.WithKeys("n", "N", "esc"),
+			key.WithHelp("n", "cancel"),
+		),
+	}
+}
+
+// AllBindings returns every binding in the key map, including disabled ones,
+// in the order they're declared in. This is useful for rendering a reference
+// of keyboard shortcuts.
+func (k KeyMap) AllBindings() []key.Binding {
+	return []key.Binding{
+		k.CursorUp,
+		k.CursorDown,
+		k.GoToStart,
+		k.GoToEnd,
+		k.Filter,
+		k.ClearFilter,
+		k.PageUp,
+		k.PageDown,
+		k.GoToStartSequence,
+		k.JumpBack,
+		k.JumpForward,
+		k.Activate,
+		k.ToggleCollapse,
+		k.ExpandAll,
+		k.CollapseAll,
+		k.Search,
+		k.NextMatch,
+		k.PrevMatch,
+		k.PopFilter,
+		k.ToggleFilterApplied,
+		k.CopyFilter,
+		k.MoveUp,
+		k.MoveDown,
+		k.CancelWhileFiltering,
+		k.AcceptWhileFiltering,
+		k.ClearFilterText,
+		k.AcceptCompletion,
+		k.ShowFullHelp,
+		k.CloseFullHelp,
+		k.Quit,
+		k.ForceQuit,
+		k.ConfirmQuit,
+		k.CancelQuit,
 	}
 }
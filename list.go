@@ -9,16 +9,20 @@ package list
 import (
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/cursor"
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/ansi"
 	"github.com/muesli/reflow/truncate"
 	"github.com/sahilm/fuzzy"
 )
@@ -30,6 +34,77 @@ type Item interface {
 	FilterValue() string
 }
 
+// CachedItem wraps an item and remembers its filter value after the first
+// call to FilterValue, for items which are expensive to compute it for. The
+// wrapped item's filter value must not change. Title and Description are
+// forwarded if the wrapped item has them, otherwise they're empty. Use Unwrap
+// to get the wrapped item back. The list and DefaultDelegate look through the
+// wrapper for optional interfaces such as IdentifiableItem, GroupItem,
+// KeywordItem, StyledItem and LoadingItem. See WrapCached.
+type CachedItem struct {
+	item        Item
+	filterValue string
+	cached      bool
+}
+
+// NewCachedItem returns a CachedItem wrapping the given item.
+func NewCachedItem(item Item) *CachedItem {
+	return &CachedItem{item: item}
+}
+
+// WrapCached wraps each of the given items in a CachedItem.
+func WrapCached(items []Item) []Item {
+	wrapped := make([]Item, len(items))
+	for i, item := range items {
+		wrapped[i] = NewCachedItem(item)
+	}
+	return wrapped
+}
+
+// FilterValue returns the wrapped item's filter value, which is only computed
+// once.
+func (c *CachedItem) FilterValue() string {
+	if !c.cached {
+		c.filterValue = c.item.FilterValue()
+		c.cached = true
+	}
+	return c.filterValue
+}
+
+// Title returns the wrapped item's title if it's a DefaultItem.
+func (c *CachedItem) Title() string {
+	if i, ok := c.item.(DefaultItem); ok {
+		return i.Title()
+	}
+	return ""
+}
+
+// Description returns the wrapped item's description if it has one.
+func (c *CachedItem) Description() string {
+	if i, ok := c.item.(interface{ Description() string }); ok {
+		return i.Description()
+	}
+	return ""
+}
+
+// Unwrap returns the wrapped item.
+func (c *CachedItem) Unwrap() Item {
+	return c.item
+}
+
+// unwrapItem returns the item wrapped by a wrapper such as CachedItem, or the
+// item itself if it isn't wrapped. Optional interfaces are checked on the
+// unwrapped item, since wrappers don't implement them.
+func unwrapItem(item Item) Item {
+	for {
+		w, ok := item.(interface{ Unwrap() Item })
+		if !ok {
+			return item
+		}
+		item = w.Unwrap()
+	}
+}
+
 // ItemDelegate encapsulates the general functionality for all list items. The
 // benefit to separating this logic from the item itself is that you can change
 // the functionality of items without changing the actual items themselves.
@@ -37,7 +112,11 @@ type Item interface {
 // Note that if the delegate also implements help.KeyMap delegate-related
 // help items will be added to the help view.
 type ItemDelegate interface {
-	// Render renders the item's view.
+	// Render renders the item's view. When rendering the list it's only
+	// called for the items in view, as reported by Model.RenderedRange, so
+	// delegates can do expensive work per item without paying for items
+	// which are off-screen. Model.ViewRange is the exception, rendering
+	// whichever items it's asked for.
 	Render(w io.Writer, m Model, index int, item Item)
 
 	// Height is the height of the list item.
@@ -53,8 +132,87 @@ type ItemDelegate interface {
 	Update(msg tea.Msg, m *Model) tea.Cmd
 }
 
+// ItemSource provides items on demand, for lists too large to hold in memory,
+// such as lists backed by a database or a huge file. See Model.SetItemSource.
+type ItemSource interface {
+	// Len returns the number of items.
+	Len() int
+
+	// Item returns the item at the given index, which is always in range.
+	Item(i int) Item
+}
+
+// IdentifiableItem is an optional interface for items with a stable identity.
+// Identities let the list recognize an item after it has been replaced: when
+// the items are set again the selection stays on the same item, wherever it
+// moved to, and DeduplicateItems removes items with the same identity. Set
+// Model.IDFunc to identify items without changing them.
+//
+// Without an identity, the selection keeps its position instead, and
+// DeduplicateItems falls back to comparing the items' filter values.
+type IdentifiableItem interface {
+	Item
+	ID() string
+}
+
+// GroupItem is an optional interface for items which head a group. The items
+// following a group item, up to the next one, belong to its group. Groups can
+// be collapsed to hide their items, see Model.ToggleCollapse, and filtering
+// expands groups containing matches. Groups aren't supported with item
+// sources.
+type GroupItem interface {
+	Item
+	GroupTitle() string
+}
+
+// KeywordItem is an optional interface for items with hidden keywords. When
+// filtering, an item which doesn't match on its filter value still matches if
+// one of its keywords does. Such items are ranked after those matching on
+// their filter values, and nothing is highlighted in them since keywords
+// aren't displayed.
+type KeywordItem interface {
+	Item
+	FilterKeywords() []string
+}
+
+// SeparatorDelegate is an optional interface for delegates which draw a line
+// between items. The separator takes the place of the first line of spacing,
+// so Spacing should be at least one.
+type SeparatorDelegate interface {
+	ItemDelegate
+	RenderSeparator(w io.Writer, m Model)
+}
+
+// ItemUpdater is an optional interface for delegates which handle messages
+// targeted at a single item, such as per-item animations. See ItemMsg.
+type ItemUpdater interface {
+	// UpdateItem is called with the message wrapped in an ItemMsg and the
+	// index of the item it targets in the list's items.
+	UpdateItem(msg tea.Msg, m *Model, index int) tea.Cmd
+}
+
+// SelectionDelegate is an optional interface for delegates which need to know
+// when an item gains or loses the selection, for instance to fetch a preview
+// for the selected item. The list calls these methods at the end of Update
+// whenever the selected index has changed since the previous call. The index
+// refers to the items currently available, see AvailableItems.
+type SelectionDelegate interface {
+	OnSelect(m *Model, index int) tea.Cmd
+	OnDeselect(m *Model, index int) tea.Cmd
+}
+
+// ItemMsg wraps a message targeted at the item at the given index in the
+// list's items. The list unwraps it and passes it to the delegate if the
+// delegate implements ItemUpdater. The message is dropped otherwise, or if the
+// index is out of bounds.
+type ItemMsg struct {
+	Index int
+	Msg   tea.Msg
+}
+
 type filteredItem struct {
 	item    Item  // item matched
+	index   int   // index of the item in the master set of items
 	matches []int // rune indices of matched items
 }
 
@@ -69,12 +227,17 @@ func (f filteredItems) items() []Item {
 }
 
 // FilterMatchesMsg contains data about items matched during filtering. The
-// message should be routed to Update for processing.
-type FilterMatchesMsg []filteredItem
+// message should be routed to Update for processing. Matches for a filter
+// which has changed since they were computed are discarded.
+type FilterMatchesMsg struct {
+	generation int
+	matches    []filteredItem
+}
 
 // FilterFunc takes a term and a list of strings to search through
 // (defined by Item#FilterValue).
-// It should return a sorted list of ranks.
+// It should return a sorted list of ranks. The term has leading and trailing
+// whitespace trimmed and is never empty.
 type FilterFunc func(string, []string) []Rank
 
 // Rank defines a rank for a given item.
@@ -114,8 +277,28 @@ func UnsortedFilter(term string, targets []string) []Rank {
 	return result
 }
 
+// filterBatchMsg carries the matches found in a batch of items when filtering
+// incrementally, see Model.FilterBatchSize. Batches from an earlier generation
+// are stale and discarded.
+type filterBatchMsg struct {
+	generation int
+	start      int // index of the first item scanned
+	end        int // index after the last item scanned
+	matches    []filteredItem
+}
+
 type statusMessageTimeoutMsg struct{}
 
+// keySequenceTimeoutMsg is sent when a pending key sequence has timed out. It
+// carries the ID of the sequence it belongs to.
+type keySequenceTimeoutMsg int
+
+// scrollAnimationMsg advances an animated jump. Messages from an animation
+// that has since been finished early are ignored.
+type scrollAnimationMsg struct {
+	id int
+}
+
 // FilterState describes the current filtering state on the model.
 type FilterState int
 
@@ -135,6 +318,56 @@ func (f FilterState) String() string {
 	}[f]
 }
 
+// FooterPosition describes where the footer row is rendered relative to the
+// help view.
+type FooterPosition int
+
+// Possible footer positions.
+const (
+	FooterAboveHelp FooterPosition = iota // footer is rendered above the help
+	FooterBelowHelp                       // footer is rendered below the help
+)
+
+// TitlePosition describes where the title bar, which holds the title and the
+// filter input, is rendered relative to the items.
+type TitlePosition int
+
+// Possible title positions.
+const (
+	TitleTop    TitlePosition = iota // title bar is rendered above the items
+	TitleBottom                      // title bar is rendered below the items
+)
+
+// EscapeBehavior describes what pressing the ClearFilter key, escape by
+// default, does while browsing when there's no filter or search to clear.
+type EscapeBehavior int
+
+// Possible escape behaviors.
+const (
+	EscapeClearThenQuit EscapeBehavior = iota // quit, if the key is also bound to Quit
+	EscapeClearOnly                           // do nothing
+	EscapeCustom                              // call Model.OnEscape
+)
+
+// HelpStyle describes how the short help is laid out. The full help is shown
+// the same way in every style.
+type HelpStyle int
+
+// Possible help styles.
+const (
+	HelpDefault         HelpStyle = iota // bindings which don't fit are left out
+	HelpCompactAdaptive                  // bindings which don't fit are counted as "+N more"
+)
+
+// Clipboard is the interface used by the list to copy text, such as the
+// applied filter term.
+type Clipboard interface {
+	WriteAll(text string) error
+}
+
+// The maximum number of entries kept in the jump history.
+const maxJumps = 100
+
 // Model contains the state of this component.
 type Model struct {
 	showTitle        bool
@@ -142,11 +375,48 @@ type Model struct {
 	showStatusBar    bool
 	showHelp         bool
 	filteringEnabled bool
+	inlineFilter     bool
+	autoHeight       bool
+	inputLocked      bool
+	readOnly         bool
+
+	showFilterCharCount  bool
+	showFilterMatchCount bool
+	showFilterCompletion bool
+	showFilterSpinner    bool
+	showItemPosition     bool
+
+	// A short label, such as a count, rendered right after the title.
+	titleBadge string
 
 	itemNameSingular string
 	itemNamePlural   string
+	itemNameFunc     func(count int) string
+
+	// A non-selectable row rendered between the status bar and the items,
+	// useful for column labels. HeaderFunc takes precedence over the header
+	// set with SetHeader.
+	header     string
+	HeaderFunc func(m Model) string
+
+	// A row rendered below the items, such as a breadcrumb or a path.
+	// FooterFunc takes precedence over the footer set with SetFooter.
+	footer         string
+	footerPosition FooterPosition
+	titlePosition  TitlePosition
+	FooterFunc     func(m Model) string
+
+	escapeBehavior EscapeBehavior
+
+	// The width the help is kept within, if set. Otherwise it's the width of
+	// the list.
+	helpWidth int
+	helpStyle HelpStyle
 
-	Title             string
+	Title string
+
+	// Styles used to render the list. Once the list is in use, change them
+	// with SetStyles so the items are laid out again.
 	Styles            Styles
 	InfiniteScrolling bool
 
@@ -156,6 +426,63 @@ type Model struct {
 	// Filter is used to filter the list.
 	Filter FilterFunc
 
+	// FilterBatchSize makes filtering incremental when set: items are scanned
+	// in batches of this size, and the matches of each batch are shown as soon
+	// as they're found, along with the progress in the status bar. Matches are
+	// only ranked within their batch. Zero means all items are filtered at
+	// once.
+	FilterBatchSize int
+
+	// MaxVisibleMatches caps the number of filter matches shown, keeping the
+	// top ranked ones. Zero means no limit.
+	MaxVisibleMatches int
+
+	// PersistentHighlight is a term whose matches are highlighted in the
+	// items whether or not they're being filtered, such as the last search
+	// term after the filter has been cleared. Matches are found with Filter.
+	// While set it takes precedence over the matches of the filter. See
+	// HighlightsForItem.
+	PersistentHighlight string
+
+	// IDFunc returns the identity of an item, taking precedence over
+	// IdentifiableItem. See IdentifiableItem for what identities are used
+	// for.
+	IDFunc func(Item) string
+
+	// SectionHeaderFunc formats the title of group items rendered by
+	// DefaultDelegate, such as "Fruits (3/10)", given the number of items in
+	// the group and how many of them match the filter. When unfiltered,
+	// matched is the same as total. See GroupCounts.
+	SectionHeaderFunc func(title string, total, matched int) string
+
+	// Groups collapsed with ToggleCollapse, by the key of their group item.
+	collapsed map[string]bool
+
+	// The index of each item's group item in the master set of items, or -1
+	// for items before the first group. It's nil when there are no groups.
+	groupOf []int
+
+	// Items pinned with PinItem, by key.
+	pinned map[string]bool
+
+	// The keys of the most recently activated items, most recent first, and
+	// how many of them are shown above the other items while unfiltered,
+	// see SetShowRecents.
+	recents     []string
+	showRecents int
+	recentCount int
+
+	// The master indexes of the items available while unfiltered, in the
+	// order they're shown in, or nil when no items are hidden in collapsed
+	// groups, pinned or recent. Recent items come first and are also listed
+	// in their usual place.
+	visible []int
+
+	// The number of items in each group, and of those matching the filter,
+	// by the master index of their group item.
+	groupSizes   map[int]int
+	groupMatches map[int]int
+
 	disableQuitKeybindings bool
 
 	// Additional key mappings for the short and full help views. This allows
@@ -166,12 +493,65 @@ type Model struct {
 	AdditionalShortHelpKeys func() []key.Binding
 	AdditionalFullHelpKeys  func() []key.Binding
 
+	// OnActivate is called when the Activate keybinding is pressed while
+	// browsing. It receives the index and the selected item and may return a
+	// command.
+	OnActivate func(index int, item Item) tea.Cmd
+
+	// ConfirmQuit makes the quit keys, including ForceQuit, ask for
+	// confirmation before quitting, which is useful when quitting would lose
+	// unsaved changes. The prompt replaces the title, and the list quits only
+	// on the ConfirmQuit key; the CancelQuit key dismisses it. Pressing
+	// ForceQuit again while asked quits too.
+	ConfirmQuit bool
+
+	// OnEscape is called when the ClearFilter key is pressed with nothing to
+	// clear, if the escape behavior is EscapeCustom. It may return a command,
+	// such as one closing a parent menu.
+	OnEscape func() tea.Cmd
+
+	// OnEmptyChange is called when the list goes from showing items to
+	// showing none, or the other way around, whether because of changes to
+	// the items or the filter.
+	OnEmptyChange func(empty bool)
+
+	// OnScroll is called from Update when the range of items visible in the
+	// viewport changes. It receives the indexes, in AvailableItems(), of the
+	// first and last visible items.
+	OnScroll func(first, last int)
+
+	// OnReorder is called after an item has been moved with MoveItem,
+	// MoveItemUp or MoveItemDown, including by dragging it. It receives the
+	// item's old and new indexes in the master set of items. It isn't called
+	// when nothing moved.
+	OnReorder func(from, to int)
+
+	// Clipboard is used by the CopyFilter keybinding to copy text. Nothing is
+	// copied if it's nil.
+	Clipboard Clipboard
+
+	// OnFilterLimit is called when a keystroke is dropped because the filter
+	// input has reached its character limit. It may return a command, for
+	// instance to show a status message.
+	OnFilterLimit func() tea.Cmd
+
 	spinner     spinner.Model
 	showSpinner bool
+
+	// The size available to the list's content, which is the size set with
+	// SetSize minus the insets.
 	width       int
 	height      int
+	insetTop    int
+	insetRight  int
+	insetBottom int
+	insetLeft   int
+
 	Help        help.Model
 	FilterInput textinput.Model
+
+	// SearchInput is the input used to enter a search, see KeyMap.Search.
+	SearchInput textinput.Model
 	filterState FilterState
 
 	// How long status messages should stay visible. By default this is
@@ -181,12 +561,109 @@ type Model struct {
 	statusMessage      string
 	statusMessageTimer *time.Timer
 
+	// How long to wait for the next key of a multi-key sequence, such as
+	// GoToStartSequence. By default this is 500 milliseconds.
+	KeySequenceTimeout time.Duration
+
+	pendingKeys   []string
+	keySequenceID int
+
+	// Whether mouse events are handled, see SetMouseEnabled, and where the
+	// list is drawn on the screen, see SetOrigin.
+	mouseEnabled bool
+	originX      int
+	originY      int
+
+	// How soon a second click on the selected item has to follow the first
+	// for it to activate the item, see OnActivate. By default this is 500
+	// milliseconds.
+	DoubleClickInterval time.Duration
+
+	// The time and the available index of the last click on an item, for
+	// detecting double clicks.
+	lastClick      time.Time
+	lastClickIndex int
+
+	// The available index of the item under the mouse, or -1 if there's
+	// none. It's only tracked when the program reports mouse motion, such as
+	// with tea.WithMouseAllMotion.
+	hoverIndex int
+
+	// The available indexes of the item being dragged and of where it would
+	// be moved to, or -1 when no item is being dragged.
+	dragFrom int
+	dragTo   int
+
+	// Animated jumps. While scrolling, the viewport moves towards the
+	// target index, which is selected once it's in view.
+	scrollSteps       int
+	scrollInterval    time.Duration
+	scrollAnimationID int
+	scrolling         bool
+	scrollTarget      int
+
 	// The master set of items we're working with.
 	items []Item
 
+	// When set, items are taken from the source instead, see SetItemSource.
+	source ItemSource
+
+	// The filter values of the items, cached while filtering so they aren't
+	// collected again on every keystroke. It's updated or cleared whenever the
+	// items change, and never modified in place since filter commands share
+	// it.
+	filterTargets []string
+
 	// The index of the item selected in the AvailableItems()
 	// If AvailableItems() is empty, index is set to -1.
 	index int
+	// The selected index the delegate was last notified about, see
+	// SelectionDelegate.
+	notifiedIndex int
+	// Whether the list was empty when OnEmptyChange was last called.
+	notifiedEmpty bool
+
+	// Whether the quit confirmation prompt is shown, see ConfirmQuit.
+	confirmingQuit bool
+
+	// Whether the list is being updated by UpdateSync, which doesn't wait for
+	// delayed commands such as timers.
+	synchronous bool
+
+	// Whether a search is being entered, and the index selected when it
+	// started, which is restored if the search is cancelled.
+	searching    bool
+	searchOrigin int
+
+	// The key of the item to select once the filter has been recomputed,
+	// see SetItems and DeduplicateItems, and for ApplyDiff, how far below the
+	// top of the view to show it.
+	pendingSelection    string
+	hasPendingSelection bool
+	pendingKeyFunc      func(Item) string
+	pendingReveal       bool
+	pendingOffset       int
+
+	// The number of BeginUpdate calls without a matching EndUpdate, and the
+	// key of the item selected at the first, along with how far below the top
+	// of the view it was.
+	updates      int
+	updateKey    string
+	updateHasKey bool
+	updateOffset int
+
+	// Previously selected items, as indexes into the master set of items,
+	// and the position in that history. See KeyMap.JumpBack.
+	jumps   []int
+	jumpPos int
+
+	// Whether the viewport bounds below need to be recomputed, what they
+	// were last computed from and the height of the chrome around the items
+	// at the time, see viewportStale.
+	viewportDirty  bool
+	viewportInputs viewportInputs
+	viewportChrome int
+
 	// The index of item in the AvailableItems() being shown
 	// at the top of the list viewport.
 	firstItemIndexInView int
@@ -194,6 +671,29 @@ type Model struct {
 	// at the bottom of the list viewport.
 	lastItemIndexInView int
 
+	// Filter terms pushed with PushFilter. Items have to match all of them,
+	// as well as the filter input's value.
+	filterStack []string
+
+	// Whether the applied filter is hidden with ToggleFilterApplied. Its
+	// terms are kept in the filter input and the filter stack while the
+	// list is unfiltered.
+	filterHidden bool
+
+	// Incremental filtering: the generation of the filter currently in
+	// progress and the number of items it has scanned so far.
+	filterGeneration int
+	filterScanned    int
+
+	// Whether the results of the filter haven't arrived yet, and whether the
+	// filter is accepted once they have, see ApplyFilter.
+	filterPending bool
+	acceptPending bool
+
+	// The number of items which matched the filter, before applying
+	// MaxVisibleMatches.
+	matchCount int
+
 	// Filtered items we're currently displaying. Filtering, toggles and so on
 	// will alter this slice so we can show what is relevant. For that reason,
 	// this field should be considered ephemeral.
@@ -217,6 +717,12 @@ func New(items []Item, delegate ItemDelegate, width, height int) Model {
 	filterInput.CharLimit = 64
 	filterInput.Focus()
 
+	searchInput := textinput.New()
+	searchInput.Prompt = "Search: "
+	searchInput.PromptStyle = styles.FilterPrompt
+	searchInput.Cursor.Style = styles.FilterCursor
+	searchInput.CharLimit = 64
+
 	index := -1
 	if len(items) > 0 {
 		index = 0
@@ -235,7 +741,10 @@ func New(items []Item, delegate ItemDelegate, width, height int) Model {
 		Styles:                styles,
 		Title:                 "List",
 		FilterInput:           filterInput,
+		SearchInput:           searchInput,
 		StatusMessageLifetime: time.Second,
+		KeySequenceTimeout:    500 * time.Millisecond,
+		DoubleClickInterval:   500 * time.Millisecond,
 
 		width:    width,
 		height:   height,
@@ -244,8 +753,16 @@ func New(items []Item, delegate ItemDelegate, width, height int) Model {
 		index:    index,
 		spinner:  sp,
 		Help:     help.New(),
+
+		notifiedIndex: -1,
+		hoverIndex:    -1,
+		dragFrom:      -1,
+		dragTo:        -1,
+		notifiedEmpty: len(items) == 0,
+		viewportDirty: true,
 	}
 
+	m.updateVisible()
 	m.updateKeybindings()
 	return m
 }
@@ -265,13 +782,305 @@ func (m *Model) SetFilteringEnabled(v bool) {
 	m.updateKeybindings()
 }
 
-// FilteringEnabled returns whether or not filtering is enabled.
+// FilteringEnabled returns whether or not filtering is enabled. Filtering is
+// always disabled while items are taken from a source, see SetItemSource.
 func (m Model) FilteringEnabled() bool {
-	return m.filteringEnabled
+	return m.filteringEnabled && m.source == nil
+}
+
+// SetInlineFilter enables or disables inline filtering. When enabled the
+// filter input is always focused and every printable keystroke updates the
+// filter, while navigation keys such as the arrow keys keep moving through the
+// results. There's no separate filtering mode to enter or accept.
+//
+// While inline filtering, keys other than the navigation keys only go to the
+// filter input. They aren't passed to the delegate's Update, so keybindings
+// handled by the delegate, such as in DefaultDelegate.UpdateFunc, don't work,
+// and items can't be moved with MoveUp and MoveDown. Other messages still
+// reach the delegate.
+func (m *Model) SetInlineFilter(v bool) {
+	m.inlineFilter = v
+	m.resetFiltering()
+	if v {
+		m.FilterInput.Focus()
+	}
+	m.updateKeybindings()
+}
+
+// InlineFilter returns whether or not inline filtering is enabled.
+func (m Model) InlineFilter() bool {
+	return m.inlineFilter
+}
+
+// SetShowFilterCharCount shows or hides the number of characters typed into
+// the filter, along with the limit, next to the filter input.
+func (m *Model) SetShowFilterCharCount(v bool) {
+	m.showFilterCharCount = v
+	m.setSize(m.Width(), m.Height())
+}
+
+// ShowFilterCharCount returns whether or not the filter character count is
+// set to be rendered.
+func (m Model) ShowFilterCharCount() bool {
+	return m.showFilterCharCount
+}
+
+// SetShowFilterMatchCount shows or hides the number of items matching the
+// filter next to the filter input while it's being typed, which keeps the
+// feedback visible when the status bar is hidden.
+func (m *Model) SetShowFilterMatchCount(v bool) {
+	m.showFilterMatchCount = v
+}
+
+// ShowFilterMatchCount returns whether or not the filter match count is set
+// to be rendered.
+func (m Model) ShowFilterMatchCount() bool {
+	return m.showFilterMatchCount
+}
+
+// SetShowFilterCompletion shows or hides a completion after the filter input
+// while it's being typed, like a shell's autosuggestion: if the top match
+// starts with what's been typed, the rest of its filter value is shown dimmed
+// after the cursor, and the AcceptCompletion key fills it in.
+func (m *Model) SetShowFilterCompletion(v bool) {
+	m.showFilterCompletion = v
+	m.updateKeybindings()
+}
+
+// ShowFilterCompletion returns whether or not the filter completion is set to
+// be rendered.
+func (m Model) ShowFilterCompletion() bool {
+	return m.showFilterCompletion
+}
+
+// SetShowFilterSpinner sets whether the spinner is shown next to the filter
+// input while the matches of the filter are being computed, which is useful
+// with slow Filter functions.
+func (m *Model) SetShowFilterSpinner(v bool) {
+	m.showFilterSpinner = v
+}
+
+// ShowFilterSpinner returns whether the spinner is shown while the matches of
+// the filter are being computed.
+func (m Model) ShowFilterSpinner() bool {
+	return m.showFilterSpinner
+}
+
+// filterSpinning reports whether the spinner is shown next to the filter
+// input because the matches of the filter are being computed.
+func (m Model) filterSpinning() bool {
+	return m.showFilterSpinner && m.filterPending && m.showFilter &&
+		(m.filterState == Filtering || m.inlineFilter)
+}
+
+// filterCompletion returns the rest of the top match's filter value if it
+// starts with the filter being typed, ignoring case, and the cursor is at the
+// end of the input. It's empty otherwise, or if completions are hidden.
+func (m Model) filterCompletion() string {
+	value := m.FilterInput.Value()
+	if !m.showFilterCompletion || m.filterState != Filtering || value == "" ||
+		len(m.filteredItems) == 0 || m.FilterInput.Position() != len([]rune(value)) {
+		return ""
+	}
+	target := []rune(m.filteredItems[0].item.FilterValue())
+	typed := len([]rune(value))
+	if len(target) <= typed || !strings.EqualFold(string(target[:typed]), value) {
+		return ""
+	}
+	return string(target[typed:])
+}
+
+// SetFilterCharLimit sets the maximum number of characters that can be typed
+// into the filter. A limit of 0 or less means there's no limit.
+func (m *Model) SetFilterCharLimit(v int) {
+	m.FilterInput.CharLimit = v
+	m.setSize(m.Width(), m.Height())
+}
+
+// FilterCharLimit returns the maximum number of characters that can be typed
+// into the filter.
+func (m Model) FilterCharLimit() int {
+	return m.FilterInput.CharLimit
+}
+
+// SetScrollAnimation enables smooth scrolling when jumping to the start or end
+// of the list, or by a page. Instead of snapping, the viewport moves by the
+// given number of rows every interval until the destination is in view, which
+// is then selected. Pressing another key finishes the animation right away.
+// Steps of 0 or less disable the animation, which is the default.
+func (m *Model) SetScrollAnimation(steps int, interval time.Duration) {
+	m.finishScrollAnimation()
+	m.scrollSteps = steps
+	m.scrollInterval = interval
+}
+
+// scrollTo selects the item at the given index, animating the jump if scroll
+// animation is enabled and the item is out of view.
+func (m *Model) scrollTo(index int) tea.Cmd {
+	m.finishScrollAnimation()
+	if m.viewportStale() {
+		m.updateViewportBounds()
+	}
+	index = setInBounds(index, 0, m.availableCount()-1)
+	inView := index >= m.firstItemIndexInView && index <= m.lastItemIndexInView
+	if m.scrollSteps <= 0 || inView || m.viewportCapacity() == 0 || m.synchronous {
+		m.Select(index)
+		return nil
+	}
+
+	// Record the destination up front, the viewport scrolling towards it
+	// doesn't change the selection.
+	m.recordJump(m.index, index)
+	m.scrolling, m.scrollTarget = true, index
+	return m.stepScrollAnimation(scrollAnimationMsg{id: m.scrollAnimationID})
+}
+
+// stepScrollAnimation moves the viewport one step closer to the animation's
+// target and schedules the next step, or selects the target once it's in
+// view.
+func (m *Model) stepScrollAnimation(msg scrollAnimationMsg) tea.Cmd {
+	if msg.id != m.scrollAnimationID || !m.scrolling {
+		return nil
+	}
+
+	var (
+		target          = setInBounds(m.scrollTarget, 0, m.availableCount()-1)
+		space           = m.viewportCapacity()
+		oldFirst, first = m.firstItemIndexInView, m.firstItemIndexInView
+		oldLast         = m.lastItemIndexInView
+	)
+	if target > m.lastItemIndexInView {
+		first = min(first+m.scrollSteps, target-space+1)
+	} else if target < first {
+		first = max(first-m.scrollSteps, target)
+	}
+	m.firstItemIndexInView = max(0, first)
+	m.lastItemIndexInView = min(m.availableCount(), m.firstItemIndexInView+space) - 1
+
+	if m.OnScroll != nil && (oldFirst != m.firstItemIndexInView || oldLast != m.lastItemIndexInView) {
+		m.OnScroll(m.firstItemIndexInView, m.lastItemIndexInView)
+	}
+
+	if target < m.firstItemIndexInView || target > m.lastItemIndexInView {
+		return tea.Tick(m.scrollInterval, func(time.Time) tea.Msg {
+			return msg
+		})
+	}
+	m.finishScrollAnimation()
+	return nil
+}
+
+// finishScrollAnimation stops a running scroll animation, selecting its
+// target right away.
+func (m *Model) finishScrollAnimation() {
+	m.scrollAnimationID++
+	if m.scrolling {
+		m.scrolling = false
+		m.selectIndex(m.scrollTarget)
+	}
+}
+
+// stopScrollAnimation stops a running scroll animation without selecting its
+// target, dropping any pending steps.
+func (m *Model) stopScrollAnimation() {
+	m.scrollAnimationID++
+	m.scrolling = false
+}
+
+// SetInputLocked locks or unlocks input. While input is locked, key presses
+// other than ForceQuit are ignored, so the list can't be navigated or
+// filtered. Other messages, such as filter results and spinner ticks, are
+// still processed. This is useful to freeze the list during bulk updates.
+func (m *Model) SetInputLocked(v bool) {
+	m.inputLocked = v
+}
+
+// InputLocked returns whether or not input is locked.
+func (m Model) InputLocked() bool {
+	return m.inputLocked
+}
+
+// SetReadOnly sets whether the keybindings which change the items, such as
+// MoveUp and MoveDown, and dragging items with the mouse are disabled.
+// Navigating and filtering still work, and the items can still be changed
+// with methods such as MoveItemUp.
+func (m *Model) SetReadOnly(v bool) {
+	m.readOnly = v
+	m.updateKeybindings()
+}
+
+// ReadOnly returns whether the keybindings which change the items are
+// disabled.
+func (m Model) ReadOnly() bool {
+	return m.readOnly
+}
+
+// SetMouseEnabled sets whether the list handles mouse events, selecting
+// clicked items, highlighting the hovered item and moving dragged items. It's
+// disabled by default, so programs which enable mouse events for other
+// purposes aren't affected.
+func (m *Model) SetMouseEnabled(v bool) {
+	m.mouseEnabled = v
+	if !v {
+		m.hoverIndex = -1
+		m.dragFrom, m.dragTo = -1, -1
+	}
+}
+
+// MouseEnabled returns whether the list handles mouse events.
+func (m Model) MouseEnabled() bool {
+	return m.mouseEnabled
+}
+
+// SetOrigin sets the column and row of the screen at which the top left
+// corner of the list, including its insets, is drawn. Mouse events report
+// screen coordinates, so this is needed to find the item under the mouse
+// when the list is rendered below or beside other content.
+func (m *Model) SetOrigin(x, y int) {
+	m.originX, m.originY = x, y
+}
+
+// Origin returns the position of the list on the screen set with SetOrigin.
+func (m Model) Origin() (x, y int) {
+	return m.originX, m.originY
+}
+
+// SetAutoHeight sets whether the list should only be as tall as its content.
+// When enabled, the list doesn't reserve blank space below the items when
+// there are fewer of them than fit, but it never grows beyond the configured
+// height.
+func (m *Model) SetAutoHeight(v bool) {
+	m.viewportDirty = true
+	m.autoHeight = v
+}
+
+// AutoHeight returns whether or not the list only takes up as much height as
+// its content needs.
+func (m Model) AutoHeight() bool {
+	return m.autoHeight
+}
+
+// SetStyles sets the styles used to render the list.
+func (m *Model) SetStyles(s Styles) {
+	m.viewportDirty = true
+	m.Styles = s
+}
+
+// SetTitleBadge sets a short label, such as an unread count, to be rendered
+// right after the title. Set it to an empty string to remove the badge.
+func (m *Model) SetTitleBadge(v string) {
+	m.viewportDirty = true
+	m.titleBadge = v
+}
+
+// TitleBadge returns the label rendered after the title.
+func (m Model) TitleBadge() string {
+	return m.titleBadge
 }
 
 // SetShowTitle shows or hides the title bar.
 func (m *Model) SetShowTitle(v bool) {
+	m.viewportDirty = true
 	m.showTitle = v
 }
 
@@ -287,6 +1096,7 @@ func (m Model) ShowTitle() bool {
 //
 // To disable filtering entirely use EnableFiltering.
 func (m *Model) SetShowFilter(v bool) {
+	m.viewportDirty = true
 	m.showFilter = v
 }
 
@@ -301,6 +1111,7 @@ func (m Model) ShowFilter() bool {
 // SetShowStatusBar shows or hides the view that displays metadata about the
 // list, such as item counts.
 func (m *Model) SetShowStatusBar(v bool) {
+	m.viewportDirty = true
 	m.showStatusBar = v
 }
 
@@ -309,6 +1120,18 @@ func (m Model) ShowStatusBar() bool {
 	return m.showStatusBar
 }
 
+// SetShowItemPosition sets whether the status bar shows the position of the
+// selected item among the available items, such as "4/20".
+func (m *Model) SetShowItemPosition(v bool) {
+	m.showItemPosition = v
+}
+
+// ShowItemPosition returns whether the status bar shows the position of the
+// selected item.
+func (m Model) ShowItemPosition() bool {
+	return m.showItemPosition
+}
+
 // SetStatusBarItemName defines a replacement for the item's identifier.
 // Defaults to item/items.
 func (m *Model) SetStatusBarItemName(singular, plural string) {
@@ -321,8 +1144,116 @@ func (m Model) StatusBarItemName() (string, string) {
 	return m.itemNameSingular, m.itemNamePlural
 }
 
+// SetStatusBarItemNameFunc sets a function that returns the item's identifier
+// for a given count. When set, it overrides the singular and plural names set
+// with SetStatusBarItemName, which allows for custom phrasing and languages
+// with more complex plural rules. Pass nil to restore the default behavior.
+func (m *Model) SetStatusBarItemNameFunc(f func(count int) string) {
+	m.itemNameFunc = f
+}
+
+// itemName returns the item's identifier for the given count.
+func (m Model) itemName(count int) string {
+	if m.itemNameFunc != nil {
+		return m.itemNameFunc(count)
+	}
+	if count == 1 {
+		return m.itemNameSingular
+	}
+	return m.itemNamePlural
+}
+
+// SetHeader sets a header row rendered between the status bar and the items.
+// The header doesn't scroll and can't be selected. Pass an empty string to
+// remove it.
+func (m *Model) SetHeader(v string) {
+	m.viewportDirty = true
+	m.header = v
+}
+
+// Header returns the header row set with SetHeader.
+func (m Model) Header() string {
+	return m.header
+}
+
+// SetFooter sets a footer row rendered below the items. Pass an empty string
+// to remove it.
+func (m *Model) SetFooter(v string) {
+	m.viewportDirty = true
+	m.footer = v
+}
+
+// Footer returns the footer row set with SetFooter.
+func (m Model) Footer() string {
+	return m.footer
+}
+
+// SetFooterPosition sets whether the footer is rendered above or below the
+// help view. By default it's rendered above.
+func (m *Model) SetFooterPosition(p FooterPosition) {
+	m.footerPosition = p
+}
+
+// FooterPosition returns where the footer is rendered relative to the help.
+func (m Model) FooterPosition() FooterPosition {
+	return m.footerPosition
+}
+
+// SetTitlePosition sets whether the title bar, along with the filter input, is
+// rendered above or below the items. By default it's rendered above. Below,
+// it's rendered between the items and the footer and help.
+func (m *Model) SetTitlePosition(p TitlePosition) {
+	m.titlePosition = p
+}
+
+// TitlePosition returns where the title bar is rendered relative to the
+// items.
+func (m Model) TitlePosition() TitlePosition {
+	return m.titlePosition
+}
+
+// SetEscapeBehavior sets what the ClearFilter key, escape by default, does
+// when there's no filter or search to clear. By default it falls through to
+// the Quit key, which is also bound to escape, so escape quits.
+func (m *Model) SetEscapeBehavior(b EscapeBehavior) {
+	m.escapeBehavior = b
+}
+
+// EscapeBehavior returns what the ClearFilter key does when there's nothing to
+// clear.
+func (m Model) EscapeBehavior() EscapeBehavior {
+	return m.escapeBehavior
+}
+
+// SetHelpWidth sets the width the help is kept within, independently of the
+// width of the list. Bindings which don't fit are left out, and anything wider
+// is cut off, so the help never wraps and changes height. Zero, the default,
+// uses the width of the list.
+func (m *Model) SetHelpWidth(w int) {
+	m.viewportDirty = true
+	m.helpWidth = max(0, w)
+}
+
+// HelpWidth returns the width set with SetHelpWidth.
+func (m Model) HelpWidth() int {
+	return m.helpWidth
+}
+
+// SetHelpStyle sets how the short help is laid out. With HelpCompactAdaptive,
+// as many bindings as fit are shown on one line, followed by the number of
+// bindings left out and the ShowFullHelp binding for seeing them all.
+func (m *Model) SetHelpStyle(v HelpStyle) {
+	m.helpStyle = v
+}
+
+// HelpStyle returns the help style set with SetHelpStyle.
+func (m Model) HelpStyle() HelpStyle {
+	return m.helpStyle
+}
+
 // SetShowHelp shows or hides the help view.
 func (m *Model) SetShowHelp(v bool) {
+	m.viewportDirty = true
 	m.showHelp = v
 }
 
@@ -331,28 +1262,93 @@ func (m Model) ShowHelp() bool {
 	return m.showHelp
 }
 
-// Items returns the items in the list.
+// Items returns the items in the list. With an item source, every item of the
+// source is collected.
 func (m Model) Items() []Item {
+	if m.source != nil {
+		items := make([]Item, m.source.Len())
+		for i := range items {
+			items[i] = m.source.Item(i)
+		}
+		return items
+	}
 	return m.items
 }
 
 // SetItems sets the items available in the list. This returns a command.
+//
+// If items have identities the selection stays on the same item, see
+// IdentifiableItem. Otherwise, or if the item is gone, the selection keeps its
+// position if it's still in bounds, or moves to the last item. If a filter is
+// active the selection is updated once the filter has been recomputed.
 func (m *Model) SetItems(i []Item) tea.Cmd {
+	m.viewportDirty = true
 	var cmd tea.Cmd
+	id, identified := m.itemID(m.SelectedItem())
 	m.items = i
+	m.source = nil
+	m.filterTargets = nil
+	m.updateVisible()
+	m.jumps, m.jumpPos = nil, 0
 
 	if m.filterState != Unfiltered {
 		m.filteredItems = nil
-		cmd = filterItems(*m)
+		m.pendingSelection, m.hasPendingSelection = id, identified
+		m.pendingKeyFunc = m.itemKey
+		m.pendingReveal = false
+		cmd = m.refilter()
+	} else {
+		if identified {
+			m.selectKey(m.itemKey, id, 0)
+		}
+		m.selectIndex(m.index)
 	}
 
 	m.updateKeybindings()
+	m.notifyEmpty()
 	return cmd
 }
 
-// Select selects the given index of the list and scrolls to it if needed.
+// SetItemSource makes the list take its items from the given source, which is
+// only asked for the items being rendered, instead of a slice of items. This
+// allows for lists far too large to hold in memory. Filtering isn't supported
+// with a source and is disabled, as are the methods which modify items, such
+// as SetItem and InsertItem: change the source instead and call
+// SetItemSource again to refresh the list. Items and AvailableItems collect
+// every item of the source, so avoid them on large sources. Calling SetItems
+// replaces the source, enabling filtering again unless it was disabled with
+// SetFilteringEnabled.
+func (m *Model) SetItemSource(src ItemSource) {
+	m.resetFiltering()
+	m.items = nil
+	m.source = src
+	m.filterTargets = nil
+	m.updateVisible()
+	m.jumps, m.jumpPos = nil, 0
+	m.selectIndex(m.index)
+	m.updateKeybindings()
+	m.notifyEmpty()
+}
+
+// ItemSource returns the source items are taken from, if one has been set.
+func (m Model) ItemSource() ItemSource {
+	return m.source
+}
+
+// Select selects the given index of the list and scrolls to it if needed. The
+// selection is recorded in the jump history, see KeyMap.JumpBack.
 func (m *Model) Select(index int) {
-	size := len(m.AvailableItems())
+	m.scrolling = false
+	from := m.index
+	m.selectIndex(index)
+	m.recordJump(from, m.index)
+}
+
+// selectIndex selects the given index, keeping it within bounds, without
+// recording it in the jump history.
+func (m *Model) selectIndex(index int) {
+	m.viewportDirty = true
+	size := m.availableCount()
 
 	if size == 0 {
 		m.index = -1
@@ -371,94 +1367,1209 @@ func (m *Model) Select(index int) {
 	m.index = index
 }
 
-// ResetSelected resets the selected item to the first item in the list.
-func (m *Model) ResetSelected() {
-	m.Select(0)
+// recordJump adds a change of selection between the given indexes to the jump
+// history. Entries are kept as indexes into the master set of items so they
+// survive filtering.
+func (m *Model) recordJump(from, to int) {
+	if from < 0 || to < 0 || from == to {
+		return
+	}
+	m.pushJump(m.MasterIndex(from))
+	m.pushJump(m.MasterIndex(to))
 }
 
-// ResetFilter resets the current filtering state.
-func (m *Model) ResetFilter() {
-	m.resetFiltering()
+// pushJump adds an entry after the current position in the jump history,
+// discarding any entries after it. Nothing is added if the current entry is
+// the same.
+func (m *Model) pushJump(index int) {
+	if index < 0 {
+		return
+	}
+	if len(m.jumps) > 0 {
+		if m.jumps[m.jumpPos] == index {
+			return
+		}
+		m.jumps = m.jumps[:m.jumpPos+1]
+	}
+	m.jumps = append(m.jumps, index)
+	if len(m.jumps) > maxJumps {
+		m.jumps = m.jumps[len(m.jumps)-maxJumps:]
+	}
+	m.jumpPos = len(m.jumps) - 1
 }
 
-// SetItem replaces an item at the given index. This returns a command.
-func (m *Model) SetItem(index int, item Item) tea.Cmd {
-	var cmd tea.Cmd
-	m.items[index] = item
-
-	if m.filterState != Unfiltered {
-		cmd = filterItems(*m)
+// jump moves through the jump history in the given direction, selecting the
+// first entry which is currently available. Entries for items hidden by the
+// filter are skipped.
+func (m *Model) jump(direction int) {
+	if m.index < 0 || len(m.jumps) == 0 {
+		return
 	}
 
-	return cmd
+	// If the cursor has moved since the last jump, remember where it is so
+	// we can come back to it.
+	m.pushJump(m.MasterIndex(m.index))
+
+	for pos := m.jumpPos + direction; pos >= 0 && pos < len(m.jumps); pos += direction {
+		if index := m.availableIndex(m.jumps[pos]); index >= 0 {
+			m.jumpPos = pos
+			m.selectIndex(index)
+			return
+		}
+	}
+}
+
+// shiftJumps updates the jump history after the item at the given index in
+// the master set of items has been inserted (delta 1) or removed (delta -1).
+// Entries pointing at a removed item are dropped.
+func (m *Model) shiftJumps(index, delta int) {
+	jumps := m.jumps[:0]
+	pos := m.jumpPos
+	for i, j := range m.jumps {
+		switch {
+		case delta < 0 && j == index:
+			if i <= m.jumpPos {
+				pos--
+			}
+			continue
+		case j >= index:
+			j += delta
+		}
+		jumps = append(jumps, j)
+	}
+	m.jumps = jumps
+	m.jumpPos = setInBounds(pos, 0, max(0, len(jumps)-1))
+}
+
+// swapJumps updates the jump history after two items in the master set of
+// items have been swapped.
+func (m *Model) swapJumps(a, b int) {
+	for i, j := range m.jumps {
+		switch j {
+		case a:
+			m.jumps[i] = b
+		case b:
+			m.jumps[i] = a
+		}
+	}
+}
+
+// moveJumps updates the jump history after the item at index from has been
+// moved to index to, shifting the items in between.
+func (m *Model) moveJumps(from, to int) {
+	for i, j := range m.jumps {
+		switch {
+		case j == from:
+			m.jumps[i] = to
+		case from < to && j > from && j <= to:
+			m.jumps[i]--
+		case to < from && j >= to && j < from:
+			m.jumps[i]++
+		}
+	}
+}
+
+// MasterIndex returns the index in the master set of items of the item at the
+// given index in AvailableItems(), or -1 if there's no such item. Delegates
+// receive indexes into AvailableItems(), so this is useful for looking up
+// state kept by position in the master set of items. When unfiltered and no
+// group is collapsed, the index is returned as is.
+func (m Model) MasterIndex(index int) int {
+	if m.filterState == Unfiltered {
+		if m.visible == nil {
+			return index
+		}
+		if index < 0 || index >= len(m.visible) {
+			return -1
+		}
+		return m.visible[index]
+	}
+	if index < 0 || index >= len(m.filteredItems) {
+		return -1
+	}
+	return m.filteredItems[index].index
+}
+
+// availableIndex returns the index in AvailableItems() of the item at the
+// given index in the master set of items, or -1 if it isn't available.
+func (m Model) availableIndex(index int) int {
+	if m.filterState == Unfiltered {
+		if m.visible != nil {
+			// Prefer the item's usual place to its place among the recent
+			// items.
+			if i := slices.Index(m.visible[m.recentCount:], index); i >= 0 {
+				return i + m.recentCount
+			}
+			return -1
+		}
+		if index >= m.itemCount() {
+			return -1
+		}
+		return index
+	}
+	for i, f := range m.filteredItems {
+		if f.index == index {
+			return i
+		}
+	}
+	return -1
+}
+
+// ResetSelected resets the selected item to the first item in the list.
+func (m *Model) ResetSelected() {
+	m.selectIndex(0)
+}
+
+// ResetFilter resets the current filtering state.
+func (m *Model) ResetFilter() {
+	m.resetFiltering()
+}
+
+// Reset returns the list to its initial state: the filter and the search are
+// cleared, the first item is selected and scrolled into view, any scroll
+// animation and the spinner are stopped, the status message, the quit
+// confirmation and the full help are closed, partly typed key sequences are
+// dropped and the mouse's hover and drag are forgotten. Items are kept.
+func (m *Model) Reset() {
+	m.stopScrollAnimation()
+	m.resetFiltering()
+	m.ClearSearch()
+	m.confirmingQuit = false
+	m.pendingKeys = nil
+	m.hoverIndex = -1
+	m.dragFrom, m.dragTo = -1, -1
+	m.lastClick = time.Time{}
+	m.viewportDirty = true
+	m.StopSpinner()
+	m.hideStatusMessage()
+	m.Help.ShowAll = false
+	m.firstItemIndexInView = 0
+	m.lastItemIndexInView = 0
+	m.jumps, m.jumpPos = nil, 0
+	m.ResetSelected()
+	m.updateKeybindings()
+	m.notifyEmpty()
+}
+
+// Clone returns a copy of the model that can be mutated independently of the
+// original. The items and the filtered items are deep-copied so changes to
+// either list don't leak into the other. The items themselves are not copied.
+//
+// The status message and its timer are transient and are not carried over:
+// the clone starts without a status message. All other state, including the
+// selection, the filter and the viewport, is copied as is.
+func (m Model) Clone() Model {
+	if m.items != nil {
+		m.items = append([]Item(nil), m.items...)
+	}
+
+	if m.filteredItems != nil {
+		fi := make(filteredItems, len(m.filteredItems))
+		for i, f := range m.filteredItems {
+			fi[i] = filteredItem{
+				item:    f.item,
+				index:   f.index,
+				matches: append([]int(nil), f.matches...),
+			}
+		}
+		m.filteredItems = fi
+	}
+
+	m.jumps = append([]int(nil), m.jumps...)
+	m.filterStack = append([]string(nil), m.filterStack...)
+	m.collapsed = maps.Clone(m.collapsed)
+	m.pinned = maps.Clone(m.pinned)
+	m.recents = slices.Clone(m.recents)
+	m.statusMessage = ""
+	m.statusMessageTimer = nil
+
+	return m
+}
+
+// ApplyFilter sets the filter to the given term and applies it, as if the
+// user had typed the term and accepted it. An empty or whitespace-only term
+// clears the filter, and so does a term which matches nothing once the
+// matches have been computed. This returns a command which computes the
+// filtered items.
+func (m *Model) ApplyFilter(term string) tea.Cmd {
+	if m.source != nil {
+		return nil
+	}
+	m.hideStatusMessage()
+
+	if strings.TrimSpace(term) == "" {
+		m.resetFiltering()
+		m.ResetSelected()
+		return nil
+	}
+
+	m.FilterInput.SetValue(term)
+	m.FilterInput.CursorEnd()
+	if !m.inlineFilter {
+		m.FilterInput.Blur()
+	}
+	m.filteredItems = m.itemsAsFilterItems()
+	m.filterState = FilterApplied
+	m.filterHidden = false
+	m.ResetSelected()
+	m.updateKeybindings()
+
+	cmd := m.refilter()
+	m.acceptPending = true
+	return cmd
+}
+
+// ToggleFilter advances the filter to its next state, as the keybindings
+// would: when unfiltered it starts filtering, while filtering it accepts the
+// filter, and when a filter is applied it clears it. This returns a command.
+func (m *Model) ToggleFilter() tea.Cmd {
+	switch m.filterState {
+	case Unfiltered:
+		if !m.FilteringEnabled() || m.inlineFilter || m.itemCount() == 0 {
+			return nil
+		}
+		return m.startFiltering()
+	case Filtering:
+		m.acceptFilter()
+	case FilterApplied:
+		m.resetFiltering()
+	}
+	return nil
+}
+
+// ToggleFilterApplied hides the applied filter, showing all the items while
+// remembering the filter's terms, or applies the remembered terms again. The
+// selection stays on the same item. Clearing the filter while it's hidden
+// forgets the terms. This returns a command.
+func (m *Model) ToggleFilterApplied() tea.Cmd {
+	switch {
+	case m.filterState == FilterApplied:
+		selected := m.MasterIndex(m.index)
+		m.filterState = Unfiltered
+		m.filterHidden = true
+		m.filteredItems = nil
+		m.hasPendingSelection = false
+		m.filterGeneration++
+		if index := m.availableIndex(selected); index >= 0 {
+			m.index = index
+		}
+		m.selectIndex(m.index)
+		m.updateKeybindings()
+		return nil
+
+	case m.filterHidden:
+		if item := m.SelectedItem(); item != nil {
+			m.pendingSelection, m.hasPendingSelection = m.itemKey(item), true
+			m.pendingKeyFunc = m.itemKey
+			m.pendingReveal = false
+		}
+		m.filterState = FilterApplied
+		m.filterHidden = false
+		m.filteredItems = m.itemsAsFilterItems()
+		m.updateKeybindings()
+		return m.refilter()
+	}
+	return nil
+}
+
+// FilterHidden reports whether the applied filter is hidden with
+// ToggleFilterApplied.
+func (m Model) FilterHidden() bool {
+	return m.filterHidden
+}
+
+// PushFilter narrows down the list with another filter term, on top of the
+// filters applied so far. Items have to match every term, and the terms are
+// shown as breadcrumbs in the status bar. This returns a command.
+func (m *Model) PushFilter(term string) tea.Cmd {
+	term = strings.TrimSpace(term)
+	if term == "" || m.source != nil {
+		return nil
+	}
+
+	m.hideStatusMessage()
+	m.filterStack = append(m.filterStack, term)
+	if !m.inlineFilter {
+		m.FilterInput.Blur()
+	}
+	m.filterState = FilterApplied
+	m.filterHidden = false
+	m.ResetSelected()
+	m.updateKeybindings()
+
+	return m.refilter()
+}
+
+// PopFilter removes the last filter term, which is the one in the filter
+// input if there is one, or else the last one pushed with PushFilter. When no
+// terms are left the filter is cleared. This returns a command.
+func (m *Model) PopFilter() tea.Cmd {
+	switch {
+	case strings.TrimSpace(m.FilterInput.Value()) != "":
+		m.FilterInput.Reset()
+	case len(m.filterStack) > 0:
+		m.filterStack = m.filterStack[:len(m.filterStack)-1]
+	default:
+		return nil
+	}
+
+	m.hideStatusMessage()
+	m.ResetSelected()
+	if len(m.filterTerms()) == 0 {
+		m.resetFiltering()
+		return nil
+	}
+	m.updateKeybindings()
+	return m.refilter()
+}
+
+// SearchTerm returns the current search term, or an empty string if there's
+// no search. See KeyMap.Search.
+func (m Model) SearchTerm() string {
+	return strings.TrimSpace(m.SearchInput.Value())
+}
+
+// ClearSearch clears the search term, removing its highlights. The selection
+// stays where it is.
+func (m *Model) ClearSearch() {
+	m.viewportDirty = true
+	m.searching = false
+	m.SearchInput.Reset()
+	m.SearchInput.Blur()
+	m.updateKeybindings()
+}
+
+// NextMatch selects the next item after the selection which matches the
+// search term, or PersistentHighlight if there's no search, wrapping around to
+// the start of the list. Without either, when a filter is applied it selects
+// the next of the filter's matches. This returns a command.
+func (m *Model) NextMatch() tea.Cmd {
+	return m.cycleMatches(1)
+}
+
+// PrevMatch is like NextMatch, but selects the previous match, wrapping
+// around to the end of the list. This returns a command.
+func (m *Model) PrevMatch() tea.Cmd {
+	return m.cycleMatches(-1)
+}
+
+// cycleMatches selects the next match in the given direction, wrapping around.
+func (m *Model) cycleMatches(direction int) tea.Cmd {
+	matches := m.highlightMatches()
+	if len(matches) == 0 {
+		return nil
+	}
+	i := sort.SearchInts(matches, m.index)
+	if direction > 0 {
+		if i < len(matches) && matches[i] == m.index {
+			i++
+		}
+		if i == len(matches) {
+			i = 0
+		}
+	} else {
+		i--
+		if i < 0 {
+			i = len(matches) - 1
+		}
+	}
+	return m.scrollTo(matches[i])
+}
+
+// highlightMatches returns the indexes of the available items matching the
+// highlighted term, see highlightTerm, in ascending order. Without a term, all
+// the available items match if a filter is applied.
+func (m Model) highlightMatches() []int {
+	term := m.highlightTerm()
+	if term == "" {
+		if m.filterState != FilterApplied {
+			return nil
+		}
+		matches := make([]int, m.availableCount())
+		for i := range matches {
+			matches[i] = i
+		}
+		return matches
+	}
+	targets := make([]string, m.availableCount())
+	for i := range targets {
+		targets[i] = m.availableItem(i).FilterValue()
+	}
+	ranks := m.Filter(term, targets)
+	matches := make([]int, len(ranks))
+	for i, r := range ranks {
+		matches[i] = r.Index
+	}
+	sort.Ints(matches)
+	return matches
+}
+
+// highlightTerm returns the term whose matches are highlighted in the items
+// regardless of the filter, if any.
+func (m Model) highlightTerm() string {
+	if term := m.SearchTerm(); term != "" {
+		return term
+	}
+	return m.PersistentHighlight
+}
+
+// startSearch starts entering a search. This returns a command.
+func (m *Model) startSearch() tea.Cmd {
+	m.hideStatusMessage()
+	m.viewportDirty = true
+	m.searching = true
+	m.searchOrigin = m.index
+	m.SearchInput.Reset()
+	m.updateKeybindings()
+	return m.SearchInput.Focus()
+}
+
+// SetItem replaces an item at the given index. This returns a command.
+func (m *Model) SetItem(index int, item Item) tea.Cmd {
+	if m.source != nil {
+		return nil
+	}
+	var cmd tea.Cmd
+	m.items[index] = item
+	m.updateVisible()
+	m.updateKeybindings()
+
+	// Only the changed item's filter value needs to be recomputed. The cache
+	// is copied since filter commands in flight may be reading it.
+	if m.filterTargets != nil {
+		targets := append([]string(nil), m.filterTargets...)
+		targets[index] = item.FilterValue()
+		m.filterTargets = targets
+	}
+
+	if m.filterState != Unfiltered {
+		cmd = m.refilter()
+	}
+
+	return cmd
+}
+
+// MoveItem moves the item at the given index to another index, shifting the
+// items in between, and selects it. Indexes are in the master set of items.
+// Like MoveItemUp, it's a no-op while a filter is active, groups are
+// collapsed, items are pinned or recent items are shown, see SetShowRecents.
+func (m *Model) MoveItem(from, to int) {
+	if m.filterState != Unfiltered || m.visible != nil || m.source != nil ||
+		from == to || from < 0 || from >= len(m.items) || to < 0 || to >= len(m.items) {
+		return
+	}
+	item := m.items[from]
+	if from < to {
+		copy(m.items[from:to], m.items[from+1:to+1])
+	} else {
+		copy(m.items[to+1:from+1], m.items[to:from])
+	}
+	m.items[to] = item
+	m.filterTargets = nil
+	m.updateVisible()
+	m.moveJumps(from, to)
+	m.selectIndex(to)
+	if m.OnReorder != nil {
+		m.OnReorder(from, to)
+	}
 }
 
 // MoveItemUp method swaps the current item with the one above it in the list.
+// It's a no-op if the item is already at the top of the list, or while a
+// filter is active, groups are collapsed, items are pinned or recent items are
+// shown.
 func (m *Model) MoveItemUp(index int) {
-	if m.filterState == Unfiltered {
-		m.items = swapItemsInSlice(m.items, index, index-1)
-		m.CursorUp()
+	if m.filterState != Unfiltered || m.visible != nil || m.source != nil || index <= 0 || index >= len(m.items) {
+		return
+	}
+	m.items = swapItemsInSlice(m.items, index, index-1)
+	m.filterTargets = nil
+	m.updateVisible()
+	m.swapJumps(index, index-1)
+	m.CursorUp()
+	if m.OnReorder != nil {
+		m.OnReorder(index, index-1)
 	}
 }
 
 // MoveItemDown method swaps the current item with the one below it in the list.
+// It's a no-op if the item is already at the bottom of the list, or while a
+// filter is active, groups are collapsed, items are pinned or recent items are
+// shown.
 func (m *Model) MoveItemDown(index int) {
-	if m.filterState == Unfiltered {
-		m.items = swapItemsInSlice(m.items, index, index+1)
-		m.CursorDown()
+	if m.filterState != Unfiltered || m.visible != nil || m.source != nil || index < 0 || index >= len(m.items)-1 {
+		return
+	}
+	m.items = swapItemsInSlice(m.items, index, index+1)
+	m.filterTargets = nil
+	m.updateVisible()
+	m.swapJumps(index, index+1)
+	m.CursorDown()
+	if m.OnReorder != nil {
+		m.OnReorder(index, index+1)
 	}
 }
 
 // InsertItem inserts an item at the given index. If the index is out of the upper bound,
 // the item will be appended. This returns a command.
 func (m *Model) InsertItem(index int, item Item) tea.Cmd {
+	if m.source != nil {
+		return nil
+	}
+	m.viewportDirty = true
 	var cmd tea.Cmd
 	m.items = insertItemIntoSlice(m.items, item, index)
+	m.filterTargets = nil
+	m.updateVisible()
+	m.shiftJumps(index, 1)
 
 	if m.filterState != Unfiltered {
-		cmd = filterItems(*m)
+		cmd = m.refilter()
 	}
 
 	m.updateKeybindings()
+	m.notifyEmpty()
 	return cmd
 }
 
 // RemoveItem removes an item at the given index. If the index is out of bounds
 // this will be a no-op. O(n) complexity, which probably won't matter in the
 // case of a TUI.
+//
+// If the selected item is removed, the selection stays at the same position,
+// which is now the next item, or moves to the new last item.
 func (m *Model) RemoveItem(index int) {
+	if m.source != nil || index < 0 || index >= len(m.items) {
+		return
+	}
 	m.items = removeItemFromSlice(m.items, index)
+	m.filterTargets = nil
+	m.updateVisible()
+	m.shiftJumps(index, -1)
 	if m.filterState != Unfiltered {
 		m.filteredItems = removeFilterMatchFromSlice(m.filteredItems, index)
+		m.countGroupMatches()
 		if len(m.filteredItems) == 0 {
 			m.resetFiltering()
 		}
 	}
+	m.selectIndex(m.index)
+	m.notifyEmpty()
+}
+
+// DeduplicateItems removes items with the same key as an earlier item,
+// keeping the order of the remaining items. If keyFunc is nil, items are
+// compared by their identities, or by their filter values if they don't have
+// identities, see IdentifiableItem. The selection stays on the selected item,
+// or the item it duplicated. If a filter is active the items are filtered
+// again. This returns a command.
+func (m *Model) DeduplicateItems(keyFunc func(Item) string) tea.Cmd {
+	if m.source != nil {
+		return nil
+	}
+	if keyFunc == nil {
+		keyFunc = m.itemKey
+	}
+
+	seen := make(map[string]bool, len(m.items))
+	items := make([]Item, 0, len(m.items))
+	for _, item := range m.items {
+		key := keyFunc(item)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		items = append(items, item)
+	}
+	if len(items) == len(m.items) {
+		return nil
+	}
+
+	var cmd tea.Cmd
+	selected := m.SelectedItem()
+	m.viewportDirty = true
+	m.items = items
+	m.filterTargets = nil
+	m.updateVisible()
+	m.jumps, m.jumpPos = nil, 0
+
+	if m.filterState != Unfiltered {
+		m.filteredItems = nil
+		if selected != nil {
+			m.pendingSelection, m.hasPendingSelection = keyFunc(selected), true
+			m.pendingKeyFunc = keyFunc
+			m.pendingReveal = false
+		}
+		cmd = m.refilter()
+	} else {
+		if selected != nil {
+			m.selectKey(keyFunc, keyFunc(selected), 0)
+		}
+		m.selectIndex(m.index)
+	}
+
+	m.updateKeybindings()
+	return cmd
+}
+
+// BeginUpdate freezes the view while making many changes to the items, such
+// as inserting them one by one, so it doesn't jump around with every change.
+// Call EndUpdate when done. Calls can be nested, in which case the view is
+// frozen until the outermost EndUpdate.
+func (m *Model) BeginUpdate() {
+	if m.updates == 0 {
+		if m.viewportStale() {
+			m.updateViewportBounds()
+		}
+		item := m.SelectedItem()
+		m.updateHasKey = item != nil
+		if item != nil {
+			m.updateKey = m.itemKey(item)
+		}
+		m.updateOffset = m.index - m.firstItemIndexInView
+	}
+	m.updates++
+}
+
+// EndUpdate ends changes started with BeginUpdate. Once the outermost call
+// ends, the item selected when the changes began is selected again, wherever
+// it moved to, and shown at the same position in the view. Items are matched
+// by their identities, or their filter values if they don't have identities,
+// see IdentifiableItem.
+func (m *Model) EndUpdate() {
+	if m.updates == 0 {
+		return
+	}
+	m.updates--
+	if m.updates > 0 {
+		return
+	}
+
+	m.viewportDirty = true
+	if m.updateHasKey && m.selectKey(m.itemKey, m.updateKey, 0) {
+		m.RevealAt(m.updateOffset)
+	}
+	m.selectIndex(m.index)
+}
+
+// ApplyDiff replaces the items with newItems, matching items up by the key
+// returned by keyFunc, for lists showing live data. Unlike SetItems, the
+// selected item stays selected and at the same position in the view, wherever
+// it moved to, and the jump history follows the items. If keyFunc is nil,
+// items are matched by their identities, or by their filter values if they
+// don't have identities, see IdentifiableItem.
+//
+// When a filter is active, the items are only filtered again if items were
+// added, removed or reordered, or their filter values changed. Otherwise the
+// updated items simply replace the ones shown. This returns a command.
+func (m *Model) ApplyDiff(newItems []Item, keyFunc func(Item) string) tea.Cmd {
+	if m.source != nil {
+		return nil
+	}
+	if keyFunc == nil {
+		keyFunc = m.itemKey
+	}
+
+	// Items are filtered again unless only the items themselves changed.
+	var targets []string
+	unchanged := len(newItems) == len(m.items)
+	if unchanged {
+		targets = m.filterValues()
+	}
+	newIndexes := make(map[string]int, len(newItems))
+	for i, item := range newItems {
+		newIndexes[keyFunc(item)] = i
+		if unchanged && (keyFunc(m.items[i]) != keyFunc(item) ||
+			targets[i] != item.FilterValue()) {
+			unchanged = false
+		}
+	}
+
+	if m.viewportStale() {
+		m.updateViewportBounds()
+	}
+	selected := m.SelectedItem()
+	offset := m.index - m.firstItemIndexInView
+
+	// Follow the items in the jump history, dropping the ones which are gone.
+	jumps := m.jumps[:0]
+	pos := m.jumpPos
+	for i, j := range m.jumps {
+		n, ok := newIndexes[keyFunc(m.items[j])]
+		if !ok {
+			if i <= m.jumpPos {
+				pos--
+			}
+			continue
+		}
+		jumps = append(jumps, n)
+	}
+	m.jumps = jumps
+	m.jumpPos = setInBounds(pos, 0, max(0, len(jumps)-1))
+
+	m.viewportDirty = true
+	m.items = newItems
+	m.updateVisible()
+
+	var cmd tea.Cmd
+	switch {
+	case m.filterState != Unfiltered && unchanged:
+		for i := range m.filteredItems {
+			m.filteredItems[i].item = newItems[m.filteredItems[i].index]
+		}
+	case m.filterState != Unfiltered:
+		m.filteredItems = nil
+		if selected != nil {
+			m.pendingSelection, m.hasPendingSelection = keyFunc(selected), true
+			m.pendingKeyFunc = keyFunc
+			m.pendingReveal, m.pendingOffset = true, offset
+		}
+		m.filterTargets = nil
+		cmd = m.refilter()
+	default:
+		if !unchanged {
+			m.filterTargets = nil
+		}
+		if selected != nil && m.selectKey(keyFunc, keyFunc(selected), 0) {
+			m.RevealAt(offset)
+		}
+		m.selectIndex(m.index)
+	}
+
+	m.updateKeybindings()
+	m.notifyEmpty()
+	return cmd
+}
+
+// ToggleCollapse collapses the group headed by the selected item, hiding its
+// items, or expands it if it's collapsed. It's a no-op if the selected item
+// isn't a GroupItem or a filter is active.
+func (m *Model) ToggleCollapse() {
+	if m.filterState != Unfiltered || !m.isGroup(m.SelectedItem()) {
+		return
+	}
+	key := m.itemKey(m.SelectedItem())
+	m.setCollapsed(key, !m.collapsed[key])
+}
+
+// IsCollapsed reports whether the group headed by the given item is
+// collapsed.
+func (m Model) IsCollapsed(item Item) bool {
+	return m.isGroup(item) && m.collapsed[m.itemKey(item)]
+}
+
+// isGroup reports whether the item heads a group.
+func (m Model) isGroup(item Item) bool {
+	_, ok := unwrapItem(item).(GroupItem)
+	return ok && m.source == nil
+}
+
+// setCollapsed collapses or expands the group with the given key, keeping the
+// selection on the same item.
+func (m *Model) setCollapsed(key string, collapsed bool) {
+	if m.collapsed[key] == collapsed {
+		return
+	}
+	selected := m.MasterIndex(m.index)
+	if collapsed {
+		if m.collapsed == nil {
+			m.collapsed = make(map[string]bool)
+		}
+		m.collapsed[key] = true
+	} else {
+		delete(m.collapsed, key)
+	}
+	m.visibleChanged(selected)
+}
+
+// ExpandAll expands every collapsed group. This returns a command which shows
+// a status message.
+func (m *Model) ExpandAll() tea.Cmd {
+	if m.groupOf == nil {
+		return nil
+	}
+	selected := m.MasterIndex(m.index)
+	m.collapsed = nil
+	m.visibleChanged(selected)
+	return m.NewStatusMessage("Expanded all groups")
+}
+
+// CollapseAll collapses every group. If the selected item is hidden, the group
+// item heading it is selected instead. This returns a command which shows a
+// status message.
+func (m *Model) CollapseAll() tea.Cmd {
+	if m.groupOf == nil {
+		return nil
+	}
+	selected := m.MasterIndex(m.index)
+	m.collapsed = make(map[string]bool)
+	for _, item := range m.items {
+		if m.isGroup(item) {
+			m.collapsed[m.itemKey(item)] = true
+		}
+	}
+	m.visibleChanged(selected)
+	return m.NewStatusMessage("Collapsed all groups")
+}
+
+// visibleChanged updates the available items after groups have been collapsed
+// or expanded, or items have been pinned or unpinned. The item at the given
+// index in the master set of items stays selected, or the group item heading
+// it if it's now hidden.
+func (m *Model) visibleChanged(selected int) {
+	m.updateVisible()
+	m.pinMatchesFirst(m.filteredItems)
+	if selected >= 0 {
+		index := m.availableIndex(selected)
+		if index < 0 && m.filterState == Unfiltered && selected < len(m.groupOf) {
+			index = m.availableIndex(m.groupOf[selected])
+		}
+		if index >= 0 {
+			m.index = index
+		}
+	}
+	m.selectIndex(m.index)
+	m.updateKeybindings()
+}
+
+// updateVisible works out which group each item belongs to, which items are
+// hidden in collapsed groups and the order items are shown in while
+// unfiltered, with pinned items first. It must be called whenever the items
+// change.
+func (m *Model) updateVisible() {
+	m.viewportDirty = true
+	m.groupOf, m.visible, m.groupSizes = nil, nil, nil
+	if m.source != nil {
+		return
+	}
+
+	var pinned []bool
+	if len(m.pinned) > 0 {
+		for i, item := range m.items {
+			if m.isPinned(item) {
+				if pinned == nil {
+					pinned = make([]bool, len(m.items))
+				}
+				pinned[i] = true
+			}
+		}
+	}
+
+	if slices.ContainsFunc(m.items, m.isGroup) {
+		m.groupOf = make([]int, len(m.items))
+		m.groupSizes = make(map[int]int)
+		group, collapsed := -1, false
+		for i, item := range m.items {
+			hidden := false
+			if m.isGroup(item) {
+				group, collapsed = i, m.collapsed[m.itemKey(item)]
+			} else {
+				hidden = collapsed && (pinned == nil || !pinned[i])
+				if group >= 0 {
+					m.groupSizes[group]++
+				}
+			}
+			m.groupOf[i] = group
+			if hidden && m.visible == nil {
+				m.visible = make([]int, i, len(m.items))
+				for j := range m.visible {
+					m.visible[j] = j
+				}
+			}
+			if !hidden && m.visible != nil {
+				m.visible = append(m.visible, i)
+			}
+		}
+	}
+
+	if pinned != nil {
+		if m.visible == nil {
+			m.visible = make([]int, len(m.items))
+			for i := range m.visible {
+				m.visible[i] = i
+			}
+		}
+		slices.SortStableFunc(m.visible, func(a, b int) int {
+			return pinnedFirst(pinned[a], pinned[b])
+		})
+	}
+
+	m.recentCount = 0
+	if m.showRecents > 0 && len(m.recents) > 0 {
+		indexes := make(map[string]int, len(m.items))
+		for i := len(m.items) - 1; i >= 0; i-- {
+			indexes[m.itemKey(m.items[i])] = i
+		}
+		var recents []int
+		for _, key := range m.recents {
+			if i, ok := indexes[key]; ok {
+				recents = append(recents, i)
+			}
+		}
+		if len(recents) > 0 {
+			if m.visible == nil {
+				m.visible = make([]int, len(m.items))
+				for i := range m.visible {
+					m.visible[i] = i
+				}
+			}
+			m.visible = append(recents, m.visible...)
+			m.recentCount = len(recents)
+		}
+	}
+}
+
+// SetShowRecents sets how many of the most recently activated items are shown
+// above the other items while unfiltered, most recent first. Items are
+// tracked by key, see IdentifiableItem. Activating a recent item selects it
+// in its usual place instead. Zero, the default, doesn't show or track recent
+// items.
+//
+// While recent items are shown the items can't be reordered, like while items
+// are pinned: MoveItem, the MoveUp and MoveDown keybindings and dragging with
+// the mouse do nothing.
+func (m *Model) SetShowRecents(n int) {
+	m.showRecents = max(0, n)
+	if len(m.recents) > m.showRecents {
+		m.recents = m.recents[:m.showRecents]
+	}
+	m.visibleChanged(m.MasterIndex(m.index))
+}
+
+// ShowRecents returns how many recently activated items are shown, see
+// SetShowRecents.
+func (m Model) ShowRecents() int {
+	return m.showRecents
+}
+
+// IsRecent reports whether the item at the given index in AvailableItems() is
+// shown above the other items because it was recently activated. See
+// SetShowRecents.
+func (m Model) IsRecent(index int) bool {
+	return m.filterState == Unfiltered && index >= 0 && index < m.recentCount
+}
+
+// pushRecent records the item as the most recently activated one.
+func (m *Model) pushRecent(item Item) {
+	if m.showRecents == 0 {
+		return
+	}
+	key := m.itemKey(item)
+	recents := []string{key}
+	for _, k := range m.recents {
+		if k != key && len(recents) < m.showRecents {
+			recents = append(recents, k)
+		}
+	}
+	m.recents = recents
+	m.visibleChanged(m.MasterIndex(m.index))
+}
+
+// PinItem pins the item at the given index in the master set of items, so
+// it's shown before the other items whether or not a filter is applied.
+// Pinned items keep their order among themselves, and are still hidden by
+// filters they don't match, but not by collapsed groups. Items are pinned by
+// key, see IdentifiableItem, so they stay pinned when the items are set again.
+func (m *Model) PinItem(index int) {
+	m.setPinned(index, true)
+}
+
+// UnpinItem unpins the item at the given index in the master set of items.
+// See PinItem.
+func (m *Model) UnpinItem(index int) {
+	m.setPinned(index, false)
+}
+
+// IsPinned reports whether the item is pinned. See PinItem.
+func (m Model) IsPinned(item Item) bool {
+	return item != nil && m.isPinned(item)
+}
+
+func (m Model) isPinned(item Item) bool {
+	return m.pinned[m.itemKey(item)]
+}
+
+// setPinned pins or unpins the item at the given index in the master set of
+// items, keeping the selection on the same item.
+func (m *Model) setPinned(index int, pinned bool) {
+	if m.source != nil || index < 0 || index >= len(m.items) {
+		return
+	}
+	key := m.itemKey(m.items[index])
+	if m.pinned[key] == pinned {
+		return
+	}
+	if pinned {
+		if m.pinned == nil {
+			m.pinned = make(map[string]bool)
+		}
+		m.pinned[key] = true
+	} else {
+		delete(m.pinned, key)
+	}
+	m.visibleChanged(m.MasterIndex(m.index))
+}
+
+// pinMatchesFirst moves the pinned items among the given matches to the
+// front, keeping the order of the matches otherwise.
+func (m Model) pinMatchesFirst(matches []filteredItem) {
+	if len(m.pinned) == 0 {
+		return
+	}
+	slices.SortStableFunc(matches, func(a, b filteredItem) int {
+		return pinnedFirst(m.isPinned(a.item), m.isPinned(b.item))
+	})
+}
+
+// pinnedFirst compares items by whether they're pinned, for sorting pinned
+// items first.
+func pinnedFirst(a, b bool) int {
+	switch {
+	case a && !b:
+		return -1
+	case !a && b:
+		return 1
+	}
+	return 0
+}
+
+// countGroupMatches counts the filtered items in each group. It must be called
+// whenever the filtered items change.
+func (m *Model) countGroupMatches() {
+	m.groupMatches = nil
+	if m.groupOf == nil {
+		return
+	}
+	m.groupMatches = make(map[int]int)
+	for _, f := range m.filteredItems {
+		if f.index >= len(m.groupOf) {
+			continue
+		}
+		if group := m.groupOf[f.index]; group >= 0 && group != f.index {
+			m.groupMatches[group]++
+		}
+	}
+}
+
+// GroupCounts returns the number of items in the group headed by the item at
+// the given index in AvailableItems(), and how many of them match the filter.
+// When unfiltered, matched is the same as total. Both are 0 if the item isn't
+// a GroupItem.
+func (m Model) GroupCounts(index int) (total, matched int) {
+	index = m.MasterIndex(index)
+	if index < 0 || m.groupOf == nil || index >= len(m.groupOf) || m.groupOf[index] != index {
+		return 0, 0
+	}
+	total = m.groupSizes[index]
+	if m.filterState == Unfiltered {
+		return total, total
+	}
+	return total, m.groupMatches[index]
+}
+
+// groupTitle returns the title of the group item at the given index in
+// AvailableItems(), formatted with SectionHeaderFunc if it's set.
+func (m Model) groupTitle(index int, item GroupItem) string {
+	if m.SectionHeaderFunc == nil {
+		return item.GroupTitle()
+	}
+	total, matched := m.GroupCounts(index)
+	return m.SectionHeaderFunc(item.GroupTitle(), total, matched)
+}
+
+// expandMatchedGroups expands the collapsed groups containing the given
+// matches, so they're still shown once the filter is cleared.
+func (m *Model) expandMatchedGroups(matches []filteredItem) {
+	if len(m.collapsed) == 0 || m.groupOf == nil || len(m.filterTerms()) == 0 {
+		return
+	}
+	expanded := false
+	for _, match := range matches {
+		if match.index >= len(m.groupOf) {
+			continue
+		}
+		group := m.groupOf[match.index]
+		if group < 0 || group == match.index {
+			continue
+		}
+		key := m.itemKey(m.items[group])
+		if m.collapsed[key] {
+			delete(m.collapsed, key)
+			expanded = true
+		}
+	}
+	if expanded {
+		m.updateVisible()
+	}
 }
 
 // SetDelegate sets the item delegate.
 func (m *Model) SetDelegate(d ItemDelegate) {
+	m.viewportDirty = true
 	m.delegate = d
 }
 
-// AvailableItems returns the total items available to be shown.
+// AvailableItems returns the total items available to be shown. While
+// unfiltered, items in collapsed groups aren't available, see GroupItem.
 func (m Model) AvailableItems() []Item {
 	if m.filterState != Unfiltered {
 		return m.filteredItems.items()
 	}
-	return m.items
+	if m.visible != nil {
+		items := make([]Item, len(m.visible))
+		for i, index := range m.visible {
+			items[i] = m.items[index]
+		}
+		return items
+	}
+	return m.Items()
+}
+
+// itemCount returns the number of items in the master set of items or the
+// item source.
+func (m Model) itemCount() int {
+	if m.source != nil {
+		return m.source.Len()
+	}
+	return len(m.items)
+}
+
+// availableCount returns the number of items in AvailableItems() without
+// collecting them.
+func (m Model) availableCount() int {
+	if m.filterState != Unfiltered {
+		return len(m.filteredItems)
+	}
+	if m.visible != nil {
+		return len(m.visible)
+	}
+	return m.itemCount()
+}
+
+// availableItem returns the item at the given index in AvailableItems()
+// without collecting them. The index must be in range.
+func (m Model) availableItem(index int) Item {
+	switch {
+	case m.filterState != Unfiltered:
+		return m.filteredItems[index].item
+	case m.visible != nil:
+		return m.items[m.visible[index]]
+	case m.source != nil:
+		return m.source.Item(index)
+	default:
+		return m.items[index]
+	}
 }
 
 // SelectedItem returns the current selected item in the list.
 func (m Model) SelectedItem() Item {
 	i := m.Index()
 
-	items := m.AvailableItems()
-	if i < 0 || len(items) == 0 || len(items) <= i {
+	if i < 0 || m.availableCount() <= i {
 		return nil
 	}
 
-	return items[i]
+	return m.availableItem(i)
 }
 
 // MatchesForItem returns rune positions matched by the current filter, if any.
@@ -466,26 +2577,66 @@ func (m Model) SelectedItem() Item {
 //
 // See DefaultItemView for a usage example.
 func (m Model) MatchesForItem(index int) []int {
-	if m.filteredItems == nil || index >= len(m.filteredItems) {
+	if m.filterState == Unfiltered || m.filteredItems == nil ||
+		index < 0 || index >= len(m.filteredItems) {
 		return nil
 	}
 	return m.filteredItems[index].matches
 }
 
+// HighlightsForItem returns the rune positions to highlight in the item at the
+// given index of the available items: the matches of the search term if
+// there's a search, or of PersistentHighlight if it's set, otherwise the
+// matches of the current filter, if any. Delegates use this to decide what to
+// highlight, independently of whether the list is filtered.
+func (m Model) HighlightsForItem(index int) []int {
+	term := m.highlightTerm()
+	if term == "" {
+		return m.MatchesForItem(index)
+	}
+	if index < 0 || index >= m.availableCount() {
+		return nil
+	}
+	ranks := m.Filter(term, []string{m.availableItem(index).FilterValue()})
+	if len(ranks) == 0 {
+		return nil
+	}
+	return ranks[0].MatchedIndexes
+}
+
 // Index returns the index of the currently selected item as it appears in the
 // entire slice of items. If there are no items, returns -1.
 func (m Model) Index() int {
 	return m.index
 }
 
+// IsSelected reports whether the item at the given index in AvailableItems()
+// is selected. Delegates should use it rather than comparing the index with
+// Index, so they keep working if the way items are selected changes.
+//
+// Only the item under the cursor can be selected for now. Once several items
+// can be selected together this will report all of them, and a style for
+// selected items other than the cursor's will be added alongside.
+func (m Model) IsSelected(index int) bool {
+	return index >= 0 && index < m.availableCount() && index == m.index
+}
+
+// IsHovered reports whether the item at the given index in AvailableItems()
+// is under the mouse. This requires the mouse to be enabled, see
+// SetMouseEnabled, and the program to report mouse motion, such as with
+// tea.WithMouseAllMotion.
+func (m Model) IsHovered(index int) bool {
+	return index >= 0 && index < m.availableCount() && index == m.hoverIndex
+}
+
 // CursorUp selects the previous item.
 func (m *Model) CursorUp() {
-	m.Select(m.index - 1)
+	m.selectIndex(m.index - 1)
 }
 
 // CursorDown selects the next item.
 func (m *Model) CursorDown() {
-	m.Select(m.index + 1)
+	m.selectIndex(m.index + 1)
 }
 
 // FilterState returns the current filter state.
@@ -517,14 +2668,76 @@ func (m Model) IsFiltered() bool {
 	return m.filterState == FilterApplied
 }
 
-// Width returns the current width setting.
+// Width returns the current width setting, including insets.
 func (m Model) Width() int {
-	return m.width
+	return m.width + m.insetLeft + m.insetRight
 }
 
-// Height returns the current height setting.
+// Height returns the current height setting, including insets.
 func (m Model) Height() int {
-	return m.height
+	return m.height + m.insetTop + m.insetBottom
+}
+
+// RenderedRange returns the indexes, in AvailableItems(), of the first and
+// last items rendered by View. Apart from ViewRange, the delegate's Render is
+// never called for items outside of this range. If no items are rendered, last
+// is less than first.
+func (m Model) RenderedRange() (first, last int) {
+	if m.viewportStale() {
+		m.updateViewportBounds()
+	}
+	if m.availableCount() == 0 {
+		return 0, -1
+	}
+	return m.firstItemIndexInView, m.lastItemIndexInView
+}
+
+// IsItemVisible reports whether the item at the given index in
+// AvailableItems() is rendered by View, which is useful for deciding whether
+// to scroll to it. See RenderedRange.
+func (m Model) IsItemVisible(index int) bool {
+	first, last := m.RenderedRange()
+	return index >= first && index <= last && !m.Overflowing()
+}
+
+// MinHeight returns the smallest height the list can be given while still
+// showing its title, status bar, help and any other enabled sections along with
+// a single item. Insets are included.
+func (m Model) MinHeight() int {
+	return m.chromeHeight() + m.delegate.Height() + m.insetTop + m.insetBottom
+}
+
+// Overflowing reports whether the list's height is too small to show a single
+// item along with the title, status bar, help and other enabled sections. When
+// that's the case no items are rendered. See MinHeight.
+func (m Model) Overflowing() bool {
+	return m.Height() < m.MinHeight()
+}
+
+// SetInsets reserves space around the list, for example for a border or
+// padding the list is wrapped in. The size set with SetSize is treated as the
+// outer size and the list is rendered within what's left after the insets.
+//
+// For a lipgloss style s, a typical call looks like:
+//
+//	m.SetInsets(
+//		s.GetBorderTopSize()+s.GetPaddingTop(),
+//		s.GetBorderRightSize()+s.GetPaddingRight(),
+//		s.GetBorderBottomSize()+s.GetPaddingBottom(),
+//		s.GetBorderLeftSize()+s.GetPaddingLeft(),
+//	)
+func (m *Model) SetInsets(top, right, bottom, left int) {
+	width, height := m.Width(), m.Height()
+	m.insetTop = top
+	m.insetRight = right
+	m.insetBottom = bottom
+	m.insetLeft = left
+	m.setSize(width, height)
+}
+
+// Insets returns the insets set with SetInsets.
+func (m Model) Insets() (top, right, bottom, left int) {
+	return m.insetTop, m.insetRight, m.insetBottom, m.insetLeft
 }
 
 // SetSpinner allows to set the spinner style.
@@ -552,6 +2765,37 @@ func (m *Model) StopSpinner() {
 	m.showSpinner = false
 }
 
+// StartItemSpinner starts the spinner rendered next to items which are
+// loading, see LoadingItem, without showing it in the title bar. Call it when
+// items start loading. The spinner keeps going for as long as items in view are
+// loading, or it's shown in the title bar. Note that this returns a command.
+func (m Model) StartItemSpinner() tea.Cmd {
+	return m.spinner.Tick
+}
+
+// loadingInView returns whether any of the items in view are loading.
+func (m Model) loadingInView() bool {
+	first, last := m.RenderedRange()
+	for i := first; i <= last; i++ {
+		if item, ok := unwrapItem(m.availableItem(i)).(LoadingItem); ok && item.IsLoading() {
+			return true
+		}
+	}
+	return false
+}
+
+// SetKeyMap replaces the key mappings, enabling and disabling the new
+// bindings as appropriate for the current state of the list, such as whether
+// a filter is applied. Prefer this to assigning KeyMap directly, which leaves
+// the new bindings' enabled states as they are until the state next changes.
+func (m *Model) SetKeyMap(k KeyMap) {
+	m.KeyMap = k
+	if m.disableQuitKeybindings {
+		m.KeyMap.ForceQuit.SetEnabled(false)
+	}
+	m.updateKeybindings()
+}
+
 // DisableQuitKeybindings is a helper for disabling the keybindings used for quitting,
 // in case you want to handle this elsewhere in your application.
 func (m *Model) DisableQuitKeybindings() {
@@ -563,11 +2807,16 @@ func (m *Model) DisableQuitKeybindings() {
 // NewStatusMessage sets a new status message, which will show for a limited
 // amount of time. Note that this also returns a command.
 func (m *Model) NewStatusMessage(s string) tea.Cmd {
+	m.viewportDirty = true
 	m.statusMessage = s
 	if m.statusMessageTimer != nil {
 		m.statusMessageTimer.Stop()
 	}
 
+	if m.synchronous {
+		m.statusMessageTimer = nil
+		return nil
+	}
 	m.statusMessageTimer = time.NewTimer(m.StatusMessageLifetime)
 
 	// Wait for timeout
@@ -584,76 +2833,196 @@ func (m *Model) SetSize(width, height int) {
 
 // SetWidth sets the width of this component.
 func (m *Model) SetWidth(v int) {
-	m.setSize(v, m.height)
+	m.setSize(v, m.Height())
 }
 
 // SetHeight sets the height of this component.
 func (m *Model) SetHeight(v int) {
-	m.setSize(m.width, v)
+	m.setSize(m.Width(), v)
 }
 
 func (m *Model) setSize(width, height int) {
+	m.viewportDirty = true
 	promptWidth := lipgloss.Width(m.Styles.Title.Render(m.FilterInput.Prompt))
 
-	m.width = width
-	m.height = height
-	m.Help.Width = width
-	m.FilterInput.Width = width - promptWidth - lipgloss.Width(m.spinnerView())
+	width = max(0, width-m.insetLeft-m.insetRight)
+	height = max(0, height-m.insetTop-m.insetBottom)
+
+	m.width = width
+	m.height = height
+	m.Help.Width = width
+	m.FilterInput.Width = width - promptWidth - lipgloss.Width(m.spinnerView())
+	if m.showFilterCharCount {
+		m.FilterInput.Width -= lipgloss.Width(m.filterCharCountView(m.FilterInput.CharLimit))
+	}
+
+	// The filter input only scrolls with a positive width, since a width of
+	// 0 means it grows with its value.
+	m.FilterInput.Width = max(1, m.FilterInput.Width)
+}
+
+func (m *Model) resetFiltering() {
+	m.viewportDirty = true
+	if m.filterState == Unfiltered && !m.filterHidden {
+		return
+	}
+
+	m.filterState = Unfiltered
+	m.filterHidden = false
+	m.FilterInput.Reset()
+	m.filterStack = nil
+	m.filteredItems = nil
+	m.hasPendingSelection = false
+	m.filterGeneration++
+	m.filterPending = false
+	m.acceptPending = false
+	m.updateKeybindings()
+	m.notifyEmpty()
+}
+
+// refilter starts filtering the items again, discarding the results of any
+// incremental filtering still in progress. This returns a command.
+func (m *Model) refilter() tea.Cmd {
+	m.viewportDirty = true
+	m.filterGeneration++
+	m.filterScanned = 0
+	m.filterTargets = m.filterValues()
+	m.filterPending = true
+	if m.filterSpinning() {
+		return tea.Batch(filterItems(*m), m.spinner.Tick)
+	}
+	return filterItems(*m)
+}
+
+// filterValues returns the filter values of all items, using the cache if it
+// has been built.
+func (m Model) filterValues() []string {
+	if m.filterTargets != nil {
+		return m.filterTargets
+	}
+	targets := make([]string, len(m.items))
+	for i, item := range m.items {
+		targets[i] = item.FilterValue()
+	}
+	return targets
+}
+
+// filterInBatches reports whether filtering is done incrementally, see
+// FilterBatchSize.
+func (m Model) filterInBatches() bool {
+	return m.FilterBatchSize > 0 &&
+		m.filterState != Unfiltered &&
+		len(m.filterTerms()) > 0
+}
+
+// filterInProgress reports whether incremental filtering hasn't scanned all
+// the items yet.
+func (m Model) filterInProgress() bool {
+	return m.filterInBatches() && m.filterScanned < len(m.items)
 }
 
-func (m *Model) resetFiltering() {
-	if m.filterState == Unfiltered {
-		return
+// filterTerms returns the terms the items are filtered by: the pushed filters
+// followed by the filter input's value, if any.
+func (m Model) filterTerms() []string {
+	terms := append([]string(nil), m.filterStack...)
+	if term := strings.TrimSpace(m.FilterInput.Value()); term != "" {
+		terms = append(terms, term)
 	}
-
-	m.filterState = Unfiltered
-	m.FilterInput.Reset()
-	m.filteredItems = nil
-	m.updateKeybindings()
+	return terms
 }
 
 func (m Model) itemsAsFilterItems() filteredItems {
 	fi := make([]filteredItem, len(m.items))
 	for i, item := range m.items {
 		fi[i] = filteredItem{
-			item: item,
+			item:  item,
+			index: i,
 		}
 	}
 	return fi
 }
 
+// updateMatchKeybindings enables NextMatch and PrevMatch while there are
+// matches to step through: those of the search term, PersistentHighlight or
+// the applied filter. PersistentHighlight can be set at any time, so this is
+// also done before handling keys and rendering the help.
+func (m *Model) updateMatchKeybindings() {
+	enabled := m.itemCount() != 0 && !m.inlineFilter && !m.searching &&
+		m.filterState != Filtering &&
+		(m.highlightTerm() != "" || m.filterState == FilterApplied)
+	m.KeyMap.NextMatch.SetEnabled(enabled)
+	m.KeyMap.PrevMatch.SetEnabled(enabled)
+}
+
 // Set keybindings according to the filter state.
 func (m *Model) updateKeybindings() {
-	switch m.filterState {
-	case Filtering:
+	m.viewportDirty = true
+	switch {
+	case m.filterState == Filtering, m.searching:
 		m.KeyMap.MoveUp.SetEnabled(false)
 		m.KeyMap.MoveDown.SetEnabled(false)
 		m.KeyMap.CursorUp.SetEnabled(false)
 		m.KeyMap.CursorDown.SetEnabled(false)
 		m.KeyMap.GoToStart.SetEnabled(false)
 		m.KeyMap.GoToEnd.SetEnabled(false)
+		m.KeyMap.PageUp.SetEnabled(false)
+		m.KeyMap.PageDown.SetEnabled(false)
+		m.KeyMap.GoToStartSequence.SetEnabled(false)
+		m.KeyMap.JumpBack.SetEnabled(false)
+		m.KeyMap.JumpForward.SetEnabled(false)
 		m.KeyMap.Filter.SetEnabled(false)
 		m.KeyMap.ClearFilter.SetEnabled(false)
+		m.KeyMap.PopFilter.SetEnabled(false)
+		m.KeyMap.ToggleFilterApplied.SetEnabled(false)
+		m.KeyMap.Activate.SetEnabled(false)
+		m.KeyMap.ToggleCollapse.SetEnabled(false)
+		m.KeyMap.ExpandAll.SetEnabled(false)
+		m.KeyMap.CollapseAll.SetEnabled(false)
+		m.KeyMap.Search.SetEnabled(false)
+		m.KeyMap.NextMatch.SetEnabled(false)
+		m.KeyMap.PrevMatch.SetEnabled(false)
 		m.KeyMap.CancelWhileFiltering.SetEnabled(true)
-		m.KeyMap.AcceptWhileFiltering.SetEnabled(m.FilterInput.Value() != "")
+		m.KeyMap.ClearFilterText.SetEnabled(!m.searching)
+		m.KeyMap.AcceptCompletion.SetEnabled(m.showFilterCompletion && !m.searching)
+		if m.searching {
+			m.KeyMap.AcceptWhileFiltering.SetEnabled(true)
+		} else {
+			m.KeyMap.AcceptWhileFiltering.SetEnabled(m.FilterInput.Value() != "")
+		}
 		m.KeyMap.Quit.SetEnabled(false)
 		m.KeyMap.ShowFullHelp.SetEnabled(false)
 		m.KeyMap.CloseFullHelp.SetEnabled(false)
 
 	default:
-		hasItems := len(m.items) != 0
-		m.KeyMap.MoveUp.SetEnabled(hasItems)
-		m.KeyMap.MoveDown.SetEnabled(hasItems)
+		hasItems := m.itemCount() != 0
+		m.KeyMap.MoveUp.SetEnabled(hasItems && !m.readOnly && !m.inlineFilter)
+		m.KeyMap.MoveDown.SetEnabled(hasItems && !m.readOnly && !m.inlineFilter)
 		m.KeyMap.CursorUp.SetEnabled(hasItems)
 		m.KeyMap.CursorDown.SetEnabled(hasItems)
 
 		m.KeyMap.GoToStart.SetEnabled(hasItems)
 		m.KeyMap.GoToEnd.SetEnabled(hasItems)
-
-		m.KeyMap.Filter.SetEnabled(m.filteringEnabled && hasItems)
-		m.KeyMap.ClearFilter.SetEnabled(m.filterState == FilterApplied)
+		m.KeyMap.PageUp.SetEnabled(hasItems)
+		m.KeyMap.PageDown.SetEnabled(hasItems)
+		m.KeyMap.GoToStartSequence.SetEnabled(hasItems)
+		m.KeyMap.JumpBack.SetEnabled(hasItems)
+		m.KeyMap.JumpForward.SetEnabled(hasItems)
+		m.KeyMap.Activate.SetEnabled(hasItems)
+		hasGroups := m.groupOf != nil && m.filterState == Unfiltered
+		m.KeyMap.ToggleCollapse.SetEnabled(hasGroups)
+		m.KeyMap.ExpandAll.SetEnabled(hasGroups)
+		m.KeyMap.CollapseAll.SetEnabled(hasGroups)
+
+		m.KeyMap.Filter.SetEnabled(m.FilteringEnabled() && hasItems && !m.inlineFilter)
+		m.KeyMap.ClearFilter.SetEnabled(m.filterState == FilterApplied || m.filterHidden || m.SearchTerm() != "")
+		m.KeyMap.ToggleFilterApplied.SetEnabled((m.filterState == FilterApplied || m.filterHidden) && !m.inlineFilter)
+		m.KeyMap.Search.SetEnabled(hasItems && !m.inlineFilter)
+		m.updateMatchKeybindings()
+		m.KeyMap.PopFilter.SetEnabled(len(m.filterStack) > 0 && !m.filterHidden && !m.inlineFilter)
 		m.KeyMap.CancelWhileFiltering.SetEnabled(false)
+		m.KeyMap.ClearFilterText.SetEnabled(false)
 		m.KeyMap.AcceptWhileFiltering.SetEnabled(false)
+		m.KeyMap.AcceptCompletion.SetEnabled(false)
 		m.KeyMap.Quit.SetEnabled(!m.disableQuitKeybindings)
 
 		if m.Help.ShowAll {
@@ -667,34 +3036,69 @@ func (m *Model) updateKeybindings() {
 	}
 }
 
+// chromeHeight returns the combined height of everything rendered around the
+// items, such as the title, status bar and help.
+func (m Model) chromeHeight() int {
+	var h int
+	if m.showTitleBar() {
+		h += lipgloss.Height(m.titleView())
+	}
+	if m.showStatusBar {
+		h += lipgloss.Height(m.statusView())
+	}
+	if header := m.headerView(); header != "" {
+		h += lipgloss.Height(header)
+	}
+	if footer := m.footerView(); footer != "" {
+		h += lipgloss.Height(footer)
+	}
+	if m.showHelp {
+		h += lipgloss.Height(m.helpView())
+	}
+	return h
+}
+
 // Update viewport according to the amount of items for the current state.
+// The bounds are kept until they're marked as dirty or what they're computed
+// from changes, see viewportStale.
 func (m *Model) updateViewportBounds() {
-	index := m.Index()
-	if index < 0 {
-		m.firstItemIndexInView, m.lastItemIndexInView = 0, 0
+	// The view stays put between BeginUpdate and EndUpdate, only shrinking
+	// to the items left.
+	if m.updates > 0 {
+		last := m.availableCount() - 1
+		m.firstItemIndexInView = min(m.firstItemIndexInView, max(0, last))
+		m.lastItemIndexInView = min(m.lastItemIndexInView, last)
 		return
 	}
 
-	availHeight := m.height
+	m.viewportDirty = false
+	m.viewportInputs = m.currentViewportInputs()
+	m.viewportChrome = m.chromeHeight()
 
-	if m.showTitle || (m.showFilter && m.filteringEnabled) {
-		availHeight -= lipgloss.Height(m.titleView())
-	}
-	if m.showStatusBar {
-		availHeight -= lipgloss.Height(m.statusView())
+	// While a scroll animation runs the viewport is moved by it rather than
+	// following the selection.
+	if m.scrolling {
+		last := m.availableCount() - 1
+		m.firstItemIndexInView = setInBounds(m.firstItemIndexInView, 0, max(0, last))
+		m.lastItemIndexInView = min(last, m.firstItemIndexInView+m.capacity(m.viewportChrome)-1)
+		return
 	}
-	if m.showHelp {
-		availHeight -= lipgloss.Height(m.helpView())
+
+	index := m.Index()
+	if index < 0 {
+		m.firstItemIndexInView, m.lastItemIndexInView = 0, 0
+		return
 	}
 
-	itemHeight := m.delegate.Height() + m.delegate.Spacing()
-	availSpace := max(
-		1,
-		availHeight/itemHeight,
-	)
+	// If there's no room for a single item, show none rather than drawing
+	// outside of the list's bounds.
+	availSpace := m.capacity(m.viewportChrome)
+	if availSpace == 0 {
+		m.firstItemIndexInView, m.lastItemIndexInView = index, index-1
+		return
+	}
 
-	availItems := m.AvailableItems()
-	requiredSpace := len(availItems)
+	requiredSpace := m.availableCount()
 
 	currentFirst := m.firstItemIndexInView
 	currentLast := min(requiredSpace, currentFirst+availSpace) - 1
@@ -722,7 +3126,81 @@ func (m *Model) updateViewportBounds() {
 	}
 }
 
+// viewportInputs holds what the viewport bounds are computed from which is
+// cheap to compare. The chrome around the items would have to be rendered to
+// be measured, so what changes it marks the bounds as dirty instead.
+type viewportInputs struct {
+	width, height, itemHeight int
+	index, count              int
+	filterState               FilterState
+	showAllHelp               bool
+}
+
+func (m Model) currentViewportInputs() viewportInputs {
+	return viewportInputs{
+		width:       m.width,
+		height:      m.height,
+		itemHeight:  m.delegate.Height() + m.delegate.Spacing(),
+		index:       m.index,
+		count:       m.availableCount(),
+		filterState: m.filterState,
+		showAllHelp: m.Help.ShowAll,
+	}
+}
+
+// viewportStale returns whether the viewport bounds need to be recomputed,
+// either because they were marked as dirty or because what they were
+// computed from has changed.
+func (m Model) viewportStale() bool {
+	return m.viewportDirty || m.viewportInputs != m.currentViewportInputs()
+}
+
+// viewportCapacity returns how many items fit in the space left for items,
+// which is zero if there's no room for a single item.
+func (m Model) viewportCapacity() int {
+	if m.viewportStale() {
+		return m.capacity(m.chromeHeight())
+	}
+	return m.capacity(m.viewportChrome)
+}
+
+// capacity returns how many items fit below chrome of the given height.
+func (m Model) capacity(chrome int) int {
+	availHeight := m.height - chrome
+	if availHeight < m.delegate.Height() {
+		return 0
+	}
+	itemHeight := m.delegate.Height() + m.delegate.Spacing()
+	return max(1, availHeight/itemHeight)
+}
+
+// RevealAt scrolls the list so the selected item is shown offset items below
+// the first item in view, such as keeping it a couple of items from the top
+// for a preview. The offset is clamped to the items in view, and the list
+// doesn't scroll past its start or end, so the item may end up elsewhere near
+// the bounds of the list. The selection isn't changed.
+func (m *Model) RevealAt(offset int) {
+	if m.viewportStale() {
+		m.updateViewportBounds()
+	}
+	availSpace := m.viewportCapacity()
+	if m.index < 0 || availSpace == 0 {
+		return
+	}
+
+	oldFirst, oldLast := m.firstItemIndexInView, m.lastItemIndexInView
+	offset = setInBounds(offset, 0, availSpace-1)
+	first := max(0, min(m.index-offset, m.availableCount()-availSpace))
+	m.firstItemIndexInView = first
+	m.lastItemIndexInView = min(m.availableCount(), first+availSpace) - 1
+
+	if m.OnScroll != nil && (oldFirst != first || oldLast != m.lastItemIndexInView) {
+		m.OnScroll(first, m.lastItemIndexInView)
+	}
+}
+
 func (m *Model) hideStatusMessage() {
+	m.viewportDirty = true
 	m.statusMessage = ""
 	if m.statusMessageTimer != nil {
 		m.statusMessageTimer.Stop()
@@ -735,35 +3213,277 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.confirmingQuit {
+			return m, m.handleConfirmingQuit(msg)
+		}
+		m.updateMatchKeybindings()
+
 		if key.Matches(msg, m.KeyMap.ForceQuit) {
-			return m, tea.Quit
+			return m, m.quit()
+		}
+
+		if m.inputLocked {
+			return m, nil
+		}
+
+		// Any key press finishes a running scroll animation.
+		m.finishScrollAnimation()
+
+	case scrollAnimationMsg:
+		cmd := m.stepScrollAnimation(msg)
+		m.syncViewport()
+		m.notifyEmpty()
+		return m, tea.Batch(cmd, m.notifySelection())
+
+	case ItemMsg:
+		d, ok := m.delegate.(ItemUpdater)
+		if !ok || msg.Index < 0 || msg.Index >= m.itemCount() {
+			return m, nil
+		}
+		cmd := d.UpdateItem(msg.Msg, &m, msg.Index)
+		m.syncViewport()
+		m.notifyEmpty()
+		return m, tea.Batch(cmd, m.notifySelection())
+
+	case filterBatchMsg:
+		if msg.generation != m.filterGeneration {
+			return m, nil
+		}
+		if msg.start == 0 {
+			m.filteredItems = nil
+			m.matchCount = 0
+		}
+		m.matchCount += len(msg.matches)
+		matches := msg.matches
+		if m.MaxVisibleMatches > 0 {
+			room := max(0, m.MaxVisibleMatches-len(m.filteredItems))
+			matches = matches[:min(room, len(matches))]
+		}
+		start := len(m.filteredItems)
+		m.filteredItems = append(m.filteredItems, matches...)
+		if len(m.pinned) > 0 {
+			m.pinMatchesFirst(m.filteredItems)
+			start = 0
+		}
+		m.expandMatchedGroups(msg.matches)
+		m.countGroupMatches()
+		if m.hasPendingSelection && m.selectKey(m.pendingKeyFunc, m.pendingSelection, start) {
+			m.hasPendingSelection = false
+			if m.pendingReveal {
+				m.RevealAt(m.pendingOffset)
+			}
+		}
+		m.filterScanned = msg.end
+		if msg.end >= len(m.items) {
+			m.hasPendingSelection = false
+			m.filterPending = false
+			m.acceptAppliedFilter()
+		}
+		m.selectIndex(m.index)
+		m.syncViewport()
+		m.notifyEmpty()
+
+		var cmd tea.Cmd
+		if msg.end < len(m.items) {
+			cmd = filterBatch(m, msg.end)
 		}
+		return m, tea.Batch(cmd, m.notifySelection())
 
 	case FilterMatchesMsg:
-		m.filteredItems = filteredItems(msg)
-		return m, nil
+		if msg.generation != m.filterGeneration {
+			return m, nil
+		}
+		matches := msg.matches
+		m.filterPending = false
+		m.matchCount = len(matches)
+		m.pinMatchesFirst(matches)
+		if m.MaxVisibleMatches > 0 && len(matches) > m.MaxVisibleMatches {
+			matches = matches[:m.MaxVisibleMatches]
+		}
+		m.filteredItems = filteredItems(matches)
+		m.expandMatchedGroups(matches)
+		m.countGroupMatches()
+		if m.hasPendingSelection {
+			if m.selectKey(m.pendingKeyFunc, m.pendingSelection, 0) && m.pendingReveal {
+				m.RevealAt(m.pendingOffset)
+			}
+			m.hasPendingSelection = false
+		}
+		m.acceptAppliedFilter()
+		m.selectIndex(m.index)
+		m.syncViewport()
+		m.notifyEmpty()
+		return m, m.notifySelection()
 
 	case spinner.TickMsg:
 		newSpinnerModel, cmd := m.spinner.Update(msg)
 		m.spinner = newSpinnerModel
-		if m.showSpinner {
+		if m.showSpinner || m.loadingInView() || m.filterSpinning() {
 			cmds = append(cmds, cmd)
 		}
 
 	case statusMessageTimeoutMsg:
 		m.hideStatusMessage()
+
+	case keySequenceTimeoutMsg:
+		if int(msg) == m.keySequenceID {
+			m.pendingKeys = nil
+		}
 	}
 
-	if m.filterState == Filtering {
+	if m.inlineFilter && m.FilteringEnabled() {
+		cmds = append(cmds, m.handleInlineFiltering(msg))
+	} else if m.filterState == Filtering {
 		cmds = append(cmds, m.handleFiltering(msg))
+	} else if m.searching {
+		cmds = append(cmds, m.handleSearching(msg))
 	} else {
 		cmds = append(cmds, m.handleBrowsing(msg))
 		cmds = append(cmds, m.handleMoving(msg))
 	}
 
+	m.syncViewport()
+	m.notifyEmpty()
+	cmds = append(cmds, m.notifySelection())
+
 	return m, tea.Batch(cmds...)
 }
 
+// syncViewport updates the viewport bounds and calls OnScroll if they changed.
+func (m *Model) syncViewport() {
+	first, last := m.firstItemIndexInView, m.lastItemIndexInView
+	m.updateViewportBounds()
+	if m.OnScroll == nil {
+		return
+	}
+	if first != m.firstItemIndexInView || last != m.lastItemIndexInView {
+		m.OnScroll(m.firstItemIndexInView, m.lastItemIndexInView)
+	}
+}
+
+// UpdateSync is like Update, but runs the commands it returns right away and
+// handles the messages they produce, and the commands those return in turn,
+// until none are left. Filtering is thus finished by the time it returns,
+// which is useful for tests driving the list key by key.
+//
+// Delayed commands aren't waited for: status messages stay until the next
+// one, key sequences don't time out, jumps aren't animated and neither the
+// spinner nor the cursors of the inputs blink. Commands returned by
+// OnActivate and the delegate are run like any other, so they shouldn't
+// block.
+func (m Model) UpdateSync(msg tea.Msg) Model {
+	filterCursor, searchCursor := m.FilterInput.Cursor.Mode(), m.SearchInput.Cursor.Mode()
+	m.FilterInput.Cursor.SetMode(cursor.CursorStatic)
+	m.SearchInput.Cursor.SetMode(cursor.CursorStatic)
+	m.synchronous = true
+
+	for msgs := []tea.Msg{msg}; len(msgs) > 0; {
+		var cmd tea.Cmd
+		m, cmd = m.Update(msgs[0])
+		msgs = append(msgs[1:], runCmd(cmd)...)
+	}
+
+	m.synchronous = false
+	m.FilterInput.Cursor.SetMode(filterCursor)
+	m.SearchInput.Cursor.SetMode(searchCursor)
+	return m
+}
+
+// runCmd runs a command and returns the messages it produces, running batched
+// commands in turn. Spinner ticks are dropped, since handling them only
+// schedules the next tick.
+func runCmd(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	switch msg := cmd().(type) {
+	case nil, spinner.TickMsg:
+		return nil
+	case tea.BatchMsg:
+		var msgs []tea.Msg
+		for _, c := range msg {
+			msgs = append(msgs, runCmd(c)...)
+		}
+		return msgs
+	default:
+		return []tea.Msg{msg}
+	}
+}
+
+// itemID returns the identity of an item, if it has one. See IdentifiableItem.
+func (m Model) itemID(item Item) (string, bool) {
+	if item == nil {
+		return "", false
+	}
+	if m.IDFunc != nil {
+		return m.IDFunc(item), true
+	}
+	if i, ok := unwrapItem(item).(IdentifiableItem); ok {
+		return i.ID(), true
+	}
+	return "", false
+}
+
+// itemKey returns the identity of an item, falling back to its filter value
+// if it doesn't have one.
+func (m Model) itemKey(item Item) string {
+	if id, ok := m.itemID(item); ok {
+		return id
+	}
+	return item.FilterValue()
+}
+
+// selectKey selects the first available item from the given index on with
+// the given key, as returned by keyFunc. It reports whether an item was found.
+func (m *Model) selectKey(keyFunc func(Item) string, key string, from int) bool {
+	for i := from; i < m.availableCount(); i++ {
+		if keyFunc(m.availableItem(i)) == key {
+			m.index = i
+			m.viewportDirty = true
+			return true
+		}
+	}
+	return false
+}
+
+// notifyEmpty calls OnEmptyChange if the list went from having available
+// items to having none, or the other way around.
+func (m *Model) notifyEmpty() {
+	empty := m.availableCount() == 0
+	if empty == m.notifiedEmpty {
+		return
+	}
+	m.notifiedEmpty = empty
+	if m.OnEmptyChange != nil {
+		m.OnEmptyChange(empty)
+	}
+}
+
+// notifySelection tells the delegate about a change in the selected index, if
+// the delegate implements SelectionDelegate.
+func (m *Model) notifySelection() tea.Cmd {
+	if m.index == m.notifiedIndex {
+		return nil
+	}
+
+	prev := m.notifiedIndex
+	m.notifiedIndex = m.index
+
+	d, ok := m.delegate.(SelectionDelegate)
+	if !ok {
+		return nil
+	}
+
+	var cmds []tea.Cmd
+	if prev >= 0 && prev < m.availableCount() {
+		cmds = append(cmds, d.OnDeselect(m, prev))
+	}
+	if m.index >= 0 {
+		cmds = append(cmds, d.OnSelect(m, m.index))
+	}
+	return tea.Batch(cmds...)
+}
+
 func (m *Model) handleMoving(msg tea.Msg) tea.Cmd {
 	var cmds []tea.Cmd
 
@@ -789,15 +3509,36 @@ func (m *Model) handleBrowsing(msg tea.Msg) tea.Cmd {
 	var cmds []tea.Cmd
 
 	switch msg := msg.(type) {
+	case tea.MouseMsg:
+		cmds = append(cmds, m.handleMouse(msg))
+
 	case tea.KeyMsg:
+		completed, cmd := m.handleKeySequence(msg)
+		cmds = append(cmds, cmd)
+		if completed {
+			break
+		}
+
 		switch {
 		// Note: we match clear filter before quit because, by default, they're
-		// both mapped to escape.
+		// both mapped to escape. The clear filter binding is disabled when
+		// there's nothing to clear, in which case the escape behavior decides
+		// whether the key reaches quit.
 		case key.Matches(msg, m.KeyMap.ClearFilter):
-			m.resetFiltering()
+			if m.SearchTerm() != "" {
+				m.ClearSearch()
+			} else {
+				m.resetFiltering()
+			}
+
+		case m.escapeBehavior != EscapeClearThenQuit &&
+			slices.Contains(m.KeyMap.ClearFilter.Keys(), msg.String()):
+			if m.escapeBehavior == EscapeCustom && m.OnEscape != nil {
+				cmds = append(cmds, m.OnEscape())
+			}
 
 		case key.Matches(msg, m.KeyMap.Quit):
-			return tea.Quit
+			return m.quit()
 
 		case key.Matches(msg, m.KeyMap.CursorUp):
 			m.CursorUp()
@@ -806,35 +3547,349 @@ func (m *Model) handleBrowsing(msg tea.Msg) tea.Cmd {
 			m.CursorDown()
 
 		case key.Matches(msg, m.KeyMap.GoToStart):
-			m.ResetSelected()
+			cmds = append(cmds, m.scrollTo(0))
 
 		case key.Matches(msg, m.KeyMap.GoToEnd):
-			m.Select(len(m.items))
+			cmds = append(cmds, m.scrollTo(m.availableCount()-1))
+
+		case key.Matches(msg, m.KeyMap.PageUp):
+			cmds = append(cmds, m.scrollTo(m.index-max(1, m.viewportCapacity())))
+
+		case key.Matches(msg, m.KeyMap.PageDown):
+			cmds = append(cmds, m.scrollTo(m.index+max(1, m.viewportCapacity())))
+
+		case key.Matches(msg, m.KeyMap.JumpBack):
+			m.jump(-1)
+
+		case key.Matches(msg, m.KeyMap.JumpForward):
+			m.jump(1)
+
+		case key.Matches(msg, m.KeyMap.ToggleCollapse) &&
+			m.filterState == Unfiltered && m.isGroup(m.SelectedItem()):
+			m.ToggleCollapse()
+
+		case key.Matches(msg, m.KeyMap.ExpandAll):
+			cmds = append(cmds, m.ExpandAll())
+
+		case key.Matches(msg, m.KeyMap.CollapseAll):
+			cmds = append(cmds, m.CollapseAll())
+
+		case key.Matches(msg, m.KeyMap.Activate):
+			cmds = append(cmds, m.activate())
+
+		case key.Matches(msg, m.KeyMap.CopyFilter):
+			cmds = append(cmds, m.copyFilter())
+
+		case key.Matches(msg, m.KeyMap.PopFilter):
+			cmds = append(cmds, m.PopFilter())
+
+		case key.Matches(msg, m.KeyMap.ToggleFilterApplied):
+			cmds = append(cmds, m.ToggleFilterApplied())
 
 		case key.Matches(msg, m.KeyMap.Filter):
-			m.hideStatusMessage()
-			if m.FilterInput.Value() == "" {
-				// Populate filter with all items only if the filter is empty.
-				m.filteredItems = m.itemsAsFilterItems()
+			return m.startFiltering()
+
+		case key.Matches(msg, m.KeyMap.Search):
+			return m.startSearch()
+
+		case key.Matches(msg, m.KeyMap.NextMatch):
+			cmds = append(cmds, m.NextMatch())
+
+		case key.Matches(msg, m.KeyMap.PrevMatch):
+			cmds = append(cmds, m.PrevMatch())
+
+		case key.Matches(msg, m.KeyMap.ShowFullHelp):
+			fallthrough
+		case key.Matches(msg, m.KeyMap.CloseFullHelp):
+			m.Help.ShowAll = !m.Help.ShowAll
+		}
+	}
+
+	cmd := m.delegate.Update(msg, m)
+	cmds = append(cmds, cmd)
+
+	return tea.Batch(cmds...)
+}
+
+// activate calls OnActivate with the selected item and records it as the most
+// recent one. A recent item is selected in its usual place instead. This
+// returns a command.
+func (m *Model) activate() tea.Cmd {
+	item := m.SelectedItem()
+	if item == nil {
+		return nil
+	}
+	if m.IsRecent(m.index) {
+		m.Select(m.availableIndex(m.MasterIndex(m.index)))
+		return nil
+	}
+	var cmd tea.Cmd
+	if m.OnActivate != nil {
+		cmd = m.OnActivate(m.index, item)
+	}
+	m.pushRecent(item)
+	return cmd
+}
+
+// handleMouse selects the item which is clicked, and activates it if it's
+// clicked again within DoubleClickInterval. Pressing the mouse on an item and
+// releasing it over another moves the item there, see MoveItem. Mouse motion
+// updates the hovered item, see IsHovered. Mouse coordinates are made
+// relative to the list using its origin, see SetOrigin. Nothing is done unless
+// the mouse is enabled, see SetMouseEnabled.
+func (m *Model) handleMouse(msg tea.MouseMsg) tea.Cmd {
+	if !m.mouseEnabled {
+		return nil
+	}
+	msg.X -= m.originX
+	msg.Y -= m.originY
+	index, ok := m.itemAt(msg.Y)
+	if msg.Type == tea.MouseMotion {
+		m.hoverIndex = -1
+		if ok && msg.X >= m.insetLeft && msg.X < m.insetLeft+m.width {
+			m.hoverIndex = index
+		}
+	}
+
+	// Terminals report dragging as presses while the button is held. Motion
+	// without a button held means the release was missed, which ends the
+	// drag. Another press on the item without having dragged it elsewhere is
+	// handled as a click, since not every terminal reports releases.
+	pressedAgain := msg.Type == tea.MouseLeft && ok &&
+		index == m.dragFrom && m.dragTo == m.dragFrom
+	if m.dragFrom >= 0 && !pressedAgain {
+		switch msg.Type {
+		case tea.MouseLeft:
+			if ok {
+				m.dragTo = index
 			}
-			m.ResetSelected()
-			m.filterState = Filtering
-			m.FilterInput.CursorEnd()
-			m.FilterInput.Focus()
-			m.updateKeybindings()
-			return textinput.Blink
+		case tea.MouseRelease:
+			from, to := m.dragFrom, m.dragTo
+			m.dragFrom, m.dragTo = -1, -1
+			if from != to && m.canDrag() {
+				m.MoveItem(from, to)
+				m.lastClick = time.Time{}
+			}
+		default:
+			m.dragFrom, m.dragTo = -1, -1
+		}
+		return nil
+	}
+
+	if m.inputLocked || msg.Type != tea.MouseLeft || !ok {
+		return nil
+	}
+	if m.canDrag() {
+		m.dragFrom, m.dragTo = index, index
+	}
+
+	now := time.Now()
+	double := index == m.index && index == m.lastClickIndex &&
+		now.Sub(m.lastClick) <= m.DoubleClickInterval
+	if double {
+		// A third click starts over rather than activating again.
+		m.lastClick = time.Time{}
+		return m.activate()
+	}
+
+	m.lastClick, m.lastClickIndex = now, index
+	m.Select(index)
+	return nil
+}
+
+// canDrag returns whether items can be dragged to move them, which like
+// MoveItem requires them to be unfiltered, ungrouped, unpinned and without
+// recent items shown.
+func (m Model) canDrag() bool {
+	return !m.readOnly && !m.inputLocked && m.filterState == Unfiltered &&
+		m.visible == nil && m.source == nil
+}
+
+// itemAt returns the index of the available item rendered at the given row,
+// counted from the top of the list including its insets. It reports false if
+// there's no item there, such as on the title or between items.
+func (m Model) itemAt(y int) (int, bool) {
+	y -= m.insetTop
+	if m.showTitleBar() && m.titlePosition == TitleTop {
+		y -= lipgloss.Height(m.titleView())
+	}
+	if m.showStatusBar {
+		y -= lipgloss.Height(m.statusView())
+	}
+	if v := m.headerView(); v != "" {
+		y -= lipgloss.Height(v)
+	}
+	return m.ItemIndexAtOffset(y)
+}
+
+// ItemIndexAtOffset returns the index in AvailableItems() of the item rendered
+// at the given row of the items, counted from the top of the first item in
+// view. It reports false if there's no item there, such as on the spacing or
+// separator between items or below the last item. See RenderedRange.
+func (m Model) ItemIndexAtOffset(y int) (int, bool) {
+	if m.availableCount() == 0 {
+		return 0, false
+	}
+	first, last := m.RenderedRange()
+	stride := m.delegate.Height() + m.delegate.Spacing()
+	if y < 0 || stride <= 0 || y%stride >= m.delegate.Height() {
+		return 0, false
+	}
+	index := first + y/stride
+	if index > last {
+		return 0, false
+	}
+	return index, true
+}
+
+// quit returns the command quitting the program, or asks for confirmation
+// first if ConfirmQuit is set.
+func (m *Model) quit() tea.Cmd {
+	if !m.ConfirmQuit {
+		return tea.Quit
+	}
+	m.confirmingQuit = true
+	m.viewportDirty = true
+	return nil
+}
+
+// Updates for when the user is asked to confirm quitting. Keys other than the
+// answers are ignored.
+func (m *Model) handleConfirmingQuit(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, m.KeyMap.ConfirmQuit, m.KeyMap.ForceQuit):
+		m.confirmingQuit = false
+		m.viewportDirty = true
+		return tea.Quit
+
+	case key.Matches(msg, m.KeyMap.CancelQuit):
+		m.confirmingQuit = false
+		m.viewportDirty = true
+	}
+	return nil
+}
+
+// ConfirmingQuit returns whether the list is asking the user to confirm
+// quitting. See ConfirmQuit.
+func (m Model) ConfirmingQuit() bool {
+	return m.confirmingQuit
+}
+
+// copyFilter copies the applied filter term to the clipboard and reports the
+// result with a status message. It does nothing when no filter is applied.
+func (m *Model) copyFilter() tea.Cmd {
+	if m.filterState != FilterApplied || m.Clipboard == nil {
+		return nil
+	}
+	term := strings.TrimSpace(m.FilterValue())
+	if err := m.Clipboard.WriteAll(term); err != nil {
+		return m.NewStatusMessage("Couldn't copy filter: " + err.Error())
+	}
+	return m.NewStatusMessage(fmt.Sprintf("Copied %q", term))
+}
+
+// handleKeySequence keeps track of keys pressed in succession to match
+// multi-key sequences. It reports whether the key completed a sequence, in
+// which case the sequence's action has been performed. Keys which only start a
+// sequence are not consumed, so they can still match single-key bindings.
+func (m *Model) handleKeySequence(msg tea.KeyMsg) (bool, tea.Cmd) {
+	if !m.KeyMap.GoToStartSequence.Enabled() {
+		m.pendingKeys = nil
+		return false, nil
+	}
+
+	pending := append(append([]string(nil), m.pendingKeys...), msg.String())
+	for {
+		seq := strings.Join(pending, " ")
+		isPrefix := false
+
+		for _, k := range m.KeyMap.GoToStartSequence.Keys() {
+			if k == seq {
+				m.pendingKeys = nil
+				return true, m.scrollTo(0)
+			}
+			if strings.HasPrefix(k, seq+" ") {
+				isPrefix = true
+			}
+		}
+
+		if isPrefix {
+			m.pendingKeys = pending
+			m.keySequenceID++
+			if m.synchronous {
+				return false, nil
+			}
+			id := m.keySequenceID
+			return false, tea.Tick(m.KeySequenceTimeout, func(time.Time) tea.Msg {
+				return keySequenceTimeoutMsg(id)
+			})
+		}
+
+		// The key doesn't continue the pending sequence, but it might start a
+		// new one.
+		if len(pending) == 1 {
+			m.pendingKeys = nil
+			return false, nil
+		}
+		pending = pending[len(pending)-1:]
+	}
+}
+
+// startFiltering puts the list in the filtering state, focusing the filter
+// input.
+func (m *Model) startFiltering() tea.Cmd {
+	m.hideStatusMessage()
+	var cmd tea.Cmd
+	if m.FilterInput.Value() == "" && len(m.filterStack) == 0 {
+		// Populate filter with all items only if the filter is empty.
+		m.filteredItems = m.itemsAsFilterItems()
+	} else if m.filterHidden {
+		// Edit the hidden filter, which has to be recomputed.
+		m.filteredItems = m.itemsAsFilterItems()
+		m.filterHidden = false
+		cmd = m.refilter()
+	}
+	m.ResetSelected()
+	m.filterState = Filtering
+	m.acceptPending = false
+	m.FilterInput.CursorEnd()
+	m.FilterInput.Focus()
+	m.updateKeybindings()
+	return tea.Batch(textinput.Blink, cmd)
+}
 
-		case key.Matches(msg, m.KeyMap.ShowFullHelp):
-			fallthrough
-		case key.Matches(msg, m.KeyMap.CloseFullHelp):
-			m.Help.ShowAll = !m.Help.ShowAll
-		}
+// acceptFilter applies the filter being typed. If it's empty or nothing
+// matched, the filter is cleared instead.
+func (m *Model) acceptFilter() {
+	m.hideStatusMessage()
+
+	if len(m.items) == 0 {
+		return
 	}
 
-	cmd := m.delegate.Update(msg, m)
-	cmds = append(cmds, cmd)
+	// If we've filtered down to nothing, clear the filter
+	if m.availableCount() == 0 {
+		m.resetFiltering()
+		return
+	}
 
-	return tea.Batch(cmds...)
+	m.FilterInput.Blur()
+	m.filterState = FilterApplied
+	m.updateKeybindings()
+
+	if strings.TrimSpace(m.FilterInput.Value()) == "" && len(m.filterStack) == 0 {
+		m.resetFiltering()
+	}
+}
+
+// acceptAppliedFilter accepts a filter applied with ApplyFilter once its
+// matches have been computed, the same way the user accepts a filter.
+func (m *Model) acceptAppliedFilter() {
+	if !m.acceptPending {
+		return
+	}
+	m.acceptPending = false
+	m.acceptFilter()
 }
 
 // Updates for when a user is in the filter editing interface.
@@ -843,52 +3898,160 @@ func (m *Model) handleFiltering(msg tea.Msg) tea.Cmd {
 
 	// Handle keys
 	if msg, ok := msg.(tea.KeyMsg); ok {
+		completion := m.filterCompletion()
 		switch {
+		case completion != "" && key.Matches(msg, m.KeyMap.AcceptCompletion):
+			m.FilterInput.SetValue(m.FilterInput.Value() + completion)
+			m.FilterInput.CursorEnd()
+			return m.refilter()
+
+		case key.Matches(msg, m.KeyMap.ClearFilterText):
+			if m.FilterInput.Value() == "" {
+				return nil
+			}
+			m.FilterInput.Reset()
+			m.KeyMap.AcceptWhileFiltering.SetEnabled(false)
+			return m.refilter()
+
 		case key.Matches(msg, m.KeyMap.CancelWhileFiltering):
 			m.resetFiltering()
 			m.KeyMap.Filter.SetEnabled(true)
 			m.KeyMap.ClearFilter.SetEnabled(false)
 
 		case key.Matches(msg, m.KeyMap.AcceptWhileFiltering):
-			m.hideStatusMessage()
+			m.acceptFilter()
+		}
+	}
 
-			if len(m.items) == 0 {
-				break
-			}
+	filterChanged, inputCmd := m.updateFilterInput(msg)
+	cmds = append(cmds, inputCmd)
+
+	// If the filtering input has changed, request updated filtering
+	if filterChanged {
+		cmds = append(cmds, m.refilter())
+		m.KeyMap.AcceptWhileFiltering.SetEnabled(m.FilterInput.Value() != "")
+	}
 
-			h := m.AvailableItems()
+	return tea.Batch(cmds...)
+}
 
-			// If we've filtered down to nothing, clear the filter
-			if len(h) == 0 {
-				m.resetFiltering()
-				break
-			}
+// Updates for when a user is entering a search. Each keystroke selects the
+// first match from where the search started.
+func (m *Model) handleSearching(msg tea.Msg) tea.Cmd {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, m.KeyMap.CancelWhileFiltering):
+			m.ClearSearch()
+			m.selectIndex(m.searchOrigin)
+			return nil
 
-			m.FilterInput.Blur()
-			m.filterState = FilterApplied
+		case key.Matches(msg, m.KeyMap.AcceptWhileFiltering):
+			if m.SearchTerm() == "" {
+				m.ClearSearch()
+				return nil
+			}
+			m.viewportDirty = true
+			m.searching = false
+			m.SearchInput.Blur()
 			m.updateKeybindings()
+			m.recordJump(m.searchOrigin, m.index)
+			return nil
+		}
+	}
 
-			if m.FilterInput.Value() == "" {
-				m.resetFiltering()
+	before := m.SearchInput.Value()
+	var cmd tea.Cmd
+	m.SearchInput, cmd = m.SearchInput.Update(msg)
+	if m.SearchInput.Value() != before {
+		m.selectIndex(m.searchOrigin)
+		if matches := m.highlightMatches(); m.SearchTerm() != "" && len(matches) > 0 {
+			i := sort.SearchInts(matches, m.searchOrigin)
+			if i == len(matches) {
+				i = 0
 			}
+			m.selectIndex(matches[i])
 		}
 	}
+	return cmd
+}
 
-	// Update the filter text input component
-	newFilterInputModel, inputCmd := m.FilterInput.Update(msg)
-	filterChanged := m.FilterInput.Value() != newFilterInputModel.Value()
-	m.FilterInput = newFilterInputModel
+// Updates for when inline filtering is enabled. Navigation keys are handled
+// as if the user was browsing and all other keys edit the filter.
+func (m *Model) handleInlineFiltering(msg tea.Msg) tea.Cmd {
+	var cmds []tea.Cmd
+
+	keyMsg, isKey := msg.(tea.KeyMsg)
+	if isKey && m.isInlineNavigationKey(keyMsg) {
+		return m.handleBrowsing(msg)
+	}
+	if !isKey {
+		cmds = append(cmds, m.handleBrowsing(msg))
+	}
+
+	filterChanged, inputCmd := m.updateFilterInput(msg)
 	cmds = append(cmds, inputCmd)
 
-	// If the filtering input has changed, request updated filtering
 	if filterChanged {
-		cmds = append(cmds, filterItems(*m))
-		m.KeyMap.AcceptWhileFiltering.SetEnabled(m.FilterInput.Value() != "")
+		if m.FilterInput.Value() == "" && len(m.filterStack) == 0 {
+			m.resetFiltering()
+		} else {
+			m.filterState = FilterApplied
+			m.updateKeybindings()
+			cmds = append(cmds, m.refilter())
+		}
+		m.ResetSelected()
 	}
 
 	return tea.Batch(cmds...)
 }
 
+// updateFilterInput passes a message to the filter text input component and
+// reports whether the filter value changed. If a keystroke was dropped because
+// the input is at its character limit, OnFilterLimit is called.
+func (m *Model) updateFilterInput(msg tea.Msg) (bool, tea.Cmd) {
+	before := m.FilterInput.Value()
+
+	var cmds []tea.Cmd
+	newFilterInputModel, inputCmd := m.FilterInput.Update(msg)
+	m.FilterInput = newFilterInputModel
+	cmds = append(cmds, inputCmd)
+
+	changed := m.FilterInput.Value() != before
+
+	// The text input silently drops runes once it's full, so the only way to
+	// tell is that a printable key didn't change the value.
+	if !changed && m.OnFilterLimit != nil {
+		limit := m.FilterInput.CharLimit
+		if msg, ok := msg.(tea.KeyMsg); ok &&
+			(msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace) &&
+			limit > 0 && len([]rune(before)) >= limit {
+			cmds = append(cmds, m.OnFilterLimit())
+		}
+	}
+
+	return changed, tea.Batch(cmds...)
+}
+
+// isInlineNavigationKey returns whether a key should be handled as navigation
+// rather than as input when inline filtering is enabled. Printable keys are
+// always treated as input.
+func (m Model) isInlineNavigationKey(msg tea.KeyMsg) bool {
+	if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+		return false
+	}
+	return key.Matches(msg,
+		m.KeyMap.CursorUp,
+		m.KeyMap.CursorDown,
+		m.KeyMap.GoToStart,
+		m.KeyMap.GoToEnd,
+		m.KeyMap.PageUp,
+		m.KeyMap.PageDown,
+		m.KeyMap.ClearFilter,
+		m.KeyMap.Activate,
+		m.KeyMap.Quit,
+	)
+}
+
 // ShortHelp returns bindings to show in the abbreviated help view. It's part
 // of the help.KeyMap interface.
 func (m Model) ShortHelp() []key.Binding {
@@ -934,6 +4097,14 @@ func (m Model) FullHelp() [][]key.Binding {
 		m.KeyMap.MoveDown,
 		m.KeyMap.GoToStart,
 		m.KeyMap.GoToEnd,
+		m.KeyMap.PageUp,
+		m.KeyMap.PageDown,
+		m.KeyMap.JumpBack,
+		m.KeyMap.JumpForward,
+		m.KeyMap.Activate,
+		m.KeyMap.ToggleCollapse,
+		m.KeyMap.ExpandAll,
+		m.KeyMap.CollapseAll,
 	}}
 
 	filtering := m.filterState == Filtering
@@ -948,8 +4119,16 @@ func (m Model) FullHelp() [][]key.Binding {
 
 	listLevelBindings := []key.Binding{
 		m.KeyMap.Filter,
+		m.KeyMap.Search,
+		m.KeyMap.NextMatch,
+		m.KeyMap.PrevMatch,
 		m.KeyMap.ClearFilter,
+		m.KeyMap.ToggleFilterApplied,
+		m.KeyMap.PopFilter,
+		m.KeyMap.CopyFilter,
 		m.KeyMap.AcceptWhileFiltering,
+		m.KeyMap.AcceptCompletion,
+		m.KeyMap.ClearFilterText,
 		m.KeyMap.CancelWhileFiltering,
 	}
 
@@ -972,12 +4151,15 @@ func (m Model) View() string {
 	var (
 		sections    []string
 		availHeight = m.height
+		title       string
 	)
 
-	if m.showTitle || (m.showFilter && m.filteringEnabled) {
-		v := m.titleView()
-		sections = append(sections, v)
-		availHeight -= lipgloss.Height(v)
+	if m.showTitleBar() {
+		title = m.titleView()
+		availHeight -= lipgloss.Height(title)
+		if m.titlePosition == TitleTop {
+			sections = append(sections, title)
+		}
 	}
 
 	if m.showStatusBar {
@@ -986,22 +4168,58 @@ func (m Model) View() string {
 		availHeight -= lipgloss.Height(v)
 	}
 
+	if v := m.headerView(); v != "" {
+		sections = append(sections, v)
+		availHeight -= lipgloss.Height(v)
+	}
+
 	var help string
 	if m.showHelp {
 		help = m.helpView()
 		availHeight -= lipgloss.Height(help)
 	}
 
-	content := lipgloss.NewStyle().Height(availHeight).Render(m.populatedView())
-	sections = append(sections, content)
+	footer := m.footerView()
+	if footer != "" {
+		availHeight -= lipgloss.Height(footer)
+	}
+
+	// Don't draw items when there's no room for them, see Overflowing.
+	if availHeight >= m.delegate.Height() {
+		content := m.populatedView()
+		if !m.autoHeight {
+			content = lipgloss.NewStyle().Height(availHeight).Render(content)
+		}
+		sections = append(sections, content)
+	}
+
+	if title != "" && m.titlePosition == TitleBottom {
+		sections = append(sections, title)
+	}
+
+	if footer != "" && m.footerPosition == FooterAboveHelp {
+		sections = append(sections, footer)
+	}
 
 	if m.showHelp {
 		sections = append(sections, help)
 	}
 
+	if footer != "" && m.footerPosition == FooterBelowHelp {
+		sections = append(sections, footer)
+	}
+
 	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
+// showTitleBar returns whether the title bar is rendered. Besides the title
+// and the filter input, it shows the search input and the quit confirmation
+// prompt.
+func (m Model) showTitleBar() bool {
+	return m.showTitle || (m.showFilter && m.FilteringEnabled()) ||
+		m.searching || m.confirmingQuit
+}
+
 func (m Model) titleView() string {
 	var (
 		view          string
@@ -1018,9 +4236,42 @@ func (m Model) titleView() string {
 			m.showSpinner
 	)
 
-	// If the filter's showing, draw that. Otherwise draw the title.
-	if m.showFilter && m.filterState == Filtering {
-		view += m.FilterInput.View()
+	// If the quit prompt, filter or search is showing, draw that. Otherwise
+	// draw the title.
+	if m.confirmingQuit {
+		view += m.Styles.FilterPrompt.Render(fmt.Sprintf(
+			"Quit? (%s/%s)",
+			m.KeyMap.ConfirmQuit.Help().Key,
+			m.KeyMap.CancelQuit.Help().Key,
+		))
+	} else if m.showFilter && (m.filterState == Filtering || m.inlineFilter) {
+		// Make room for the match count, whose width varies as it changes.
+		input := m.FilterInput
+		var matchCount string
+		if m.showFilterMatchCount {
+			matchCount = " " + m.Styles.FilterMatchCount.Render(fmt.Sprintf("(%d)", m.availableCount()))
+			if input.Width > 0 {
+				input.Width = max(1, input.Width-lipgloss.Width(matchCount))
+			}
+		}
+		view += input.View()
+		if completion := m.filterCompletion(); completion != "" {
+			if input.Width > 0 {
+				room := max(0, input.Width-lipgloss.Width(input.Value()))
+				completion = truncate.String(completion, uint(room))
+			}
+			view += m.Styles.FilterCompletion.Render(completion)
+		}
+		view += matchCount
+		if m.showFilterCharCount {
+			view += m.filterCharCountView(len([]rune(m.FilterInput.Value())))
+		}
+
+		// Lists too narrow for the prompt and the input cut them off.
+		availWidth := max(0, m.width-titleBarStyle.GetHorizontalFrameSize())
+		view = truncate.String(view, uint(availWidth))
+	} else if m.searching {
+		view += m.SearchInput.View()
 	} else if m.showTitle {
 		if m.showSpinner && spinnerOnLeft {
 			view += spinnerView + spinnerLeftGap
@@ -1029,16 +4280,20 @@ func (m Model) titleView() string {
 		}
 
 		view += m.Styles.Title.Render(m.Title)
+		if m.titleBadge != "" {
+			view += m.Styles.TitleBadge.Render(m.titleBadge)
+		}
 
 		// Status message
 		if m.filterState != Filtering {
 			view += "  " + m.statusMessage
-			view = truncate.StringWithTail(view, uint(m.width-spinnerWidth), ellipsis)
+			availWidth := max(0, m.width-spinnerWidth-titleBarStyle.GetHorizontalFrameSize())
+			view = truncate.StringWithTail(view, uint(availWidth), ellipsis)
 		}
 	}
 
 	// Spinner
-	if m.showSpinner && !spinnerOnLeft {
+	if (m.showSpinner || m.filterSpinning()) && !spinnerOnLeft {
 		// Place spinner on the right
 		availSpace := m.width - lipgloss.Width(m.Styles.TitleBar.Render(view))
 		if availSpace > spinnerWidth {
@@ -1054,126 +4309,502 @@ func (m Model) titleView() string {
 }
 
 func (m Model) statusView() string {
+	return m.Styles.StatusBar.Render(m.statusText(m.Styles))
+}
+
+// statusText builds the contents of the status bar using the given styles.
+func (m Model) statusText(styles Styles) string {
 	var status string
 
-	totalItems := len(m.items)
-	availableItems := len(m.AvailableItems())
+	totalItems := m.itemCount()
+	availableItems := m.availableCount()
 
-	var itemName string
-	if availableItems != 1 {
-		itemName = m.itemNamePlural
-	} else {
-		itemName = m.itemNameSingular
-	}
+	itemsDisplay := fmt.Sprintf("%d %s", availableItems, m.itemName(availableItems))
 
-	itemsDisplay := fmt.Sprintf("%d %s", availableItems, itemName)
+	// Items hidden because of MaxVisibleMatches aren't counted as filtered.
+	matched := availableItems
+	if m.filterState != Unfiltered && m.matchCount > availableItems {
+		matched = m.matchCount
+		itemsDisplay = fmt.Sprintf("showing %d of %d matches", availableItems, matched)
+	}
 
 	if m.filterState == Filtering {
 		// Filter results
 		if availableItems == 0 {
-			status = m.Styles.StatusEmpty.Render("Nothing matched")
+			status = styles.StatusEmpty.Render("Nothing matched")
 		} else {
 			status = itemsDisplay
 		}
-	} else if len(m.items) == 0 {
+	} else if totalItems == 0 {
 		// Not filtering: no items.
-		status = m.Styles.StatusEmpty.Render("No " + m.itemNamePlural)
+		status = styles.StatusEmpty.Render("No " + m.itemName(0))
 	} else {
 		// Normal
 		filtered := m.FilterState() == FilterApplied
 
 		if filtered {
-			f := strings.TrimSpace(m.FilterInput.Value())
-			f = truncate.StringWithTail(f, 10, "…")
-			status += fmt.Sprintf("“%s” ", f)
+			var crumbs []string
+			for _, f := range m.filterTerms() {
+				f = truncate.StringWithTail(f, 10, "…")
+				crumbs = append(crumbs, fmt.Sprintf("“%s”", f))
+			}
+			status += strings.Join(crumbs, " › ") + " "
 		}
 
 		status += itemsDisplay
 	}
 
-	numFiltered := totalItems - availableItems
+	numFiltered := totalItems - matched
 	if numFiltered > 0 {
-		status += m.Styles.DividerDot.String()
-		status += m.Styles.StatusBarFilterCount.Render(
+		status += styles.DividerDot.String()
+		status += styles.StatusBarFilterCount.Render(
 			fmt.Sprintf("%d filtered", numFiltered),
 		)
 	}
-	// status += " i:" + fmt.Sprint(
-	// 	m.index,
-	// ) + " f:" + fmt.Sprint(
-	// 	m.firstItemIndexInView,
-	// ) + " l:" + fmt.Sprint(
-	// 	m.lastItemIndexInView,
-	// ) + " s:" + fmt.Sprint(
-	// 	len(m.AvailableItems()),
-	// )
+	if m.filterInProgress() {
+		status += styles.DividerDot.String()
+		status += fmt.Sprintf("filtering… %d%%", m.filterScanned*100/len(m.items))
+	}
+
+	if m.showItemPosition && availableItems > 0 && m.index >= 0 {
+		status += styles.DividerDot.String()
+		status += fmt.Sprintf("%d/%d", m.index+1, availableItems)
+	}
 
-	return m.Styles.StatusBar.Render(status)
+	return status
 }
 
-func (m Model) populatedView() string {
-	m.updateViewportBounds()
-	items := m.AvailableItems()
+// PlainView renders the visible items and the status bar without any
+// styling, one item per line. The selected item is marked with "> ". This is
+// useful for accessibility tooling, such as screen readers, and for testing.
+func (m Model) PlainView() string {
+	if m.viewportStale() {
+		m.updateViewportBounds()
+	}
+
+	plain := lipgloss.NewStyle()
+	styles := Styles{
+		StatusEmpty:          plain,
+		StatusBarFilterCount: plain,
+		DividerDot:           plain.Copy().SetString(" " + bullet + " "),
+	}
+
+	var b strings.Builder
+	b.WriteString(m.statusText(styles))
+
+	if m.availableCount() == 0 {
+		return b.String()
+	}
+
+	for i := m.firstItemIndexInView; i <= m.lastItemIndexInView; i++ {
+		b.WriteString("\n")
+		if m.IsSelected(i) {
+			b.WriteString("> ")
+		} else {
+			b.WriteString("  ")
+		}
+		b.WriteString(plainItemText(m.availableItem(i)))
+	}
+
+	return b.String()
+}
+
+// Announcement returns a short description of the list's current state, such
+// as "Item 4 of 20 selected: Foobar; filter applied: 12 matches". It reflects
+// the selection, the filter and the number of items, and is intended to be
+// routed to assistive technology such as a text-to-speech engine.
+func (m Model) Announcement() string {
+	available := m.availableCount()
+
+	var announcement string
+	switch {
+	case m.itemCount() == 0:
+		announcement = "No " + m.itemName(0)
+	case available == 0:
+		announcement = "Nothing matched"
+	default:
+		announcement = fmt.Sprintf("Item %d of %d", m.index+1, available)
+		if item := m.SelectedItem(); item != nil {
+			announcement += " selected: " + plainItemText(item)
+		}
+	}
+
+	if m.filterState == Unfiltered {
+		return announcement
+	}
+
+	matches := "matches"
+	if available == 1 {
+		matches = "match"
+	}
+	return fmt.Sprintf("%s; %s: %d %s", announcement, m.filterState, available, matches)
+}
+
+// plainItemText returns the text used to represent an item in PlainView.
+func plainItemText(item Item) string {
+	text := item.FilterValue()
+	if i, ok := unwrapItem(item).(DefaultItem); ok {
+		text = i.Title()
+	}
+	return strings.ReplaceAll(text, "\n", " ")
+}
+
+// RenderPlain renders the component like View, but without any colors or
+// other styling, so the output only depends on the list's state and size.
+// This is useful for golden file tests, which would otherwise depend on the
+// terminal's color profile and background.
+func (m Model) RenderPlain() string {
+	return stripANSI(m.View())
+}
+
+// ViewRange renders count items starting at the given index in
+// AvailableItems() through the delegate, without the title, status bar, help
+// or any other chrome, and regardless of what's scrolled into view. This is
+// useful for embedding part of the list in a larger layout and scrolling it
+// from the outside. The range is clamped to the available items. Unlike View,
+// this calls the delegate's Render for items which may be outside of
+// RenderedRange.
+func (m Model) ViewRange(first, count int) string {
+	first = max(0, first)
+	last := min(first+count, m.availableCount()) - 1
+	if last < first {
+		return ""
+	}
+	var b strings.Builder
+	m.renderItems(&b, first, last)
+	return b.String()
+}
 
+// SplitView renders the list on the left, at the given ratio of its width,
+// next to the detail string on the right, which is given the rest of the
+// width. The list is rendered at its reduced width so items are truncated to
+// fit, and the detail is cut off at the list's height. Ratios outside of 0
+// to 1 are clamped.
+func (m Model) SplitView(detail string, ratio float64) string {
+	listWidth := setInBounds(int(float64(m.width)*ratio), 0, m.width)
+	detailWidth := m.width - listWidth
+
+	m.SetWidth(listWidth + m.insetLeft + m.insetRight)
+	list := lipgloss.NewStyle().Width(listWidth).Render(m.View())
+	if detailWidth == 0 {
+		return list
+	}
+	detail = lipgloss.NewStyle().
+		Width(detailWidth).
+		MaxWidth(detailWidth).
+		MaxHeight(lipgloss.Height(list)).
+		Render(detail)
+	return lipgloss.JoinHorizontal(lipgloss.Top, list, detail)
+}
+
+// stripANSI removes ANSI escape sequences from a string.
+func stripANSI(s string) string {
+	var (
+		b     strings.Builder
+		inSeq bool
+	)
+	for _, r := range s {
+		switch {
+		case r == ansi.Marker:
+			inSeq = true
+		case inSeq:
+			if ansi.IsTerminator(r) {
+				inSeq = false
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// filterCharCountView renders the given number of characters typed into the
+// filter along with the filter's character limit.
+func (m Model) filterCharCountView(count int) string {
+	limit := m.FilterInput.CharLimit
+	if limit <= 0 {
+		return " " + m.Styles.FilterCharCount.Render(fmt.Sprint(count))
+	}
+
+	style := m.Styles.FilterCharCount
+	if count >= limit {
+		style = m.Styles.FilterCharCountLimit
+	}
+	return " " + style.Render(fmt.Sprintf("%d/%d", count, limit))
+}
+
+func (m Model) headerView() string {
+	header := m.header
+	if m.HeaderFunc != nil {
+		header = m.HeaderFunc(m)
+	}
+	if header == "" {
+		return ""
+	}
+	return m.Styles.Header.Render(header)
+}
+
+func (m Model) footerView() string {
+	footer := m.footer
+	if m.FooterFunc != nil {
+		footer = m.FooterFunc(m)
+	}
+	if footer == "" {
+		return ""
+	}
+	return m.Styles.Footer.Render(footer)
+}
+
+func (m Model) populatedView() string {
+	if m.viewportStale() {
+		m.updateViewportBounds()
+	}
 	var b strings.Builder
 
 	// Empty states
-	if len(items) == 0 {
+	if m.availableCount() == 0 {
 		if m.filterState == Filtering {
 			return ""
 		}
-		return m.Styles.NoItems.Render("No " + m.itemNamePlural + ".")
+		return m.Styles.NoItems.Render("No " + m.itemName(0) + ".")
 	}
 
-	if len(items) > 0 {
-		start := m.firstItemIndexInView
-		docs := items[m.firstItemIndexInView : m.lastItemIndexInView+1]
-
-		for i, item := range docs {
-			m.delegate.Render(&b, m, i+start, item)
-			if i != len(docs)-1 {
-				fmt.Fprint(
-					&b,
-					strings.Repeat("\n", m.delegate.Spacing()+1),
-				)
-			}
-		}
+	if m.availableCount() > 0 {
+		m.renderItems(&b, m.firstItemIndexInView, m.lastItemIndexInView)
 	}
 
+	if m.dragFrom >= 0 && m.dragTo != m.dragFrom {
+		return m.insertDragIndicator(b.String())
+	}
 	return b.String()
 }
 
+// insertDragIndicator draws a line in the rendered items where the dragged
+// item would be moved to. A line is dropped from the far end of the items so
+// they keep their height.
+func (m Model) insertDragIndicator(items string) string {
+	first, last := m.firstItemIndexInView, m.lastItemIndexInView
+	if m.dragTo < first || m.dragTo > last {
+		return items
+	}
+	var (
+		height = m.delegate.Height()
+		row    = (m.dragTo - first) * (height + m.delegate.Spacing())
+		lines  = strings.Split(items, "\n")
+		line   = m.Styles.DragIndicator.Render(strings.Repeat("─", max(1, m.width)))
+	)
+	if m.dragTo > m.dragFrom {
+		row += height
+	}
+	row = min(row, len(lines))
+	lines = slices.Insert(lines, row, line)
+	if row == len(lines)-1 {
+		return strings.Join(lines[1:], "\n")
+	}
+	return strings.Join(lines[:len(lines)-1], "\n")
+}
+
+// renderItems renders the items between the given indexes in
+// AvailableItems(), inclusive, through the delegate, separated by the
+// delegate's spacing or separator.
+func (m Model) renderItems(w io.Writer, first, last int) {
+	sep, hasSep := m.delegate.(SeparatorDelegate)
+	spacing := m.delegate.Spacing()
+
+	for i := first; i <= last; i++ {
+		m.delegate.Render(w, m, i, m.availableItem(i))
+		if i == last {
+			continue
+		}
+		if hasSep && spacing > 0 {
+			fmt.Fprint(w, "\n")
+			sep.RenderSeparator(w, m)
+			fmt.Fprint(w, strings.Repeat("\n", spacing))
+		} else {
+			fmt.Fprint(w, strings.Repeat("\n", spacing+1))
+		}
+	}
+}
+
 func (m Model) helpView() string {
-	return m.Styles.HelpStyle.Render(m.Help.View(m))
+	m.updateMatchKeybindings()
+	width := m.width
+	if m.helpWidth > 0 {
+		width = m.helpWidth
+	}
+	if width <= 0 {
+		return m.Styles.HelpStyle.Render(m.Help.View(m))
+	}
+
+	// Fit the help within its width, including the help style's padding, and
+	// cut off anything wider so the terminal never wraps it.
+	h := m.Help
+	h.Width = max(1, width-m.Styles.HelpStyle.GetHorizontalFrameSize())
+	var view string
+	if m.helpStyle == HelpCompactAdaptive && !h.ShowAll {
+		view = m.compactHelpView(h.Width)
+	} else {
+		view = h.View(m)
+	}
+	return m.Styles.HelpStyle.Copy().MaxWidth(width).Render(view)
+}
+
+// compactHelpView renders the short help on one line within the given width.
+// Bindings which don't fit are left out and counted instead, followed by the
+// ShowFullHelp binding.
+func (m Model) compactHelpView(width int) string {
+	var (
+		styles   = m.Help.Styles
+		sep      = styles.ShortSeparator.Inline(true).Render(m.Help.ShortSeparator)
+		bindings = m.ShortHelp()
+		showAll  = bindings[len(bindings)-1]
+		parts    []string
+	)
+	render := func(k, desc string) string {
+		return styles.ShortKey.Inline(true).Render(k) + " " +
+			styles.ShortDesc.Inline(true).Render(desc)
+	}
+	for _, kb := range bindings[:len(bindings)-1] {
+		if kb.Enabled() {
+			parts = append(parts, render(kb.Help().Key, kb.Help().Desc))
+		}
+	}
+	var tail []string
+	if showAll.Enabled() {
+		tail = append(tail, render(showAll.Help().Key, showAll.Help().Desc))
+	}
+
+	line := strings.Join(append(parts, tail...), sep)
+	if lipgloss.Width(line) <= width {
+		return line
+	}
+	for n := len(parts) - 1; n >= 0; n-- {
+		more := styles.ShortDesc.Inline(true).Render(fmt.Sprintf("+%d more", len(parts)-n))
+		line = strings.Join(append(append(parts[:n:n], more), tail...), sep)
+		if lipgloss.Width(line) <= width {
+			break
+		}
+	}
+	return line
 }
 
 func (m Model) spinnerView() string {
 	return m.spinner.View()
 }
 
+// filterItems returns a command which filters the items against the current
+// filter value. Leading and trailing whitespace is trimmed from the value
+// before it's passed to the filter function, so a whitespace-only value
+// matches every item.
 func filterItems(m Model) tea.Cmd {
+	if m.filterInBatches() {
+		return filterBatch(m, 0)
+	}
+	return func() tea.Msg {
+		msg := FilterMatchesMsg{generation: m.filterGeneration}
+		if len(m.filterTerms()) == 0 || m.filterState == Unfiltered {
+			msg.matches = m.itemsAsFilterItems() // return nothing
+		} else {
+			msg.matches = matchItems(m, 0, len(m.items))
+		}
+		return msg
+	}
+}
+
+// filterBatch returns a command which filters the items from the given index,
+// up to FilterBatchSize of them.
+func filterBatch(m Model, start int) tea.Cmd {
 	return func() tea.Msg {
-		if m.FilterInput.Value() == "" || m.filterState == Unfiltered {
-			return FilterMatchesMsg(m.itemsAsFilterItems()) // return nothing
+		end := min(start+m.FilterBatchSize, len(m.items))
+		return filterBatchMsg{
+			generation: m.filterGeneration,
+			start:      start,
+			end:        end,
+			matches:    matchItems(m, start, end),
 		}
+	}
+}
+
+// matchItems returns the items between the given indexes which match all the
+// filter terms.
+func matchItems(m Model, start, end int) []filteredItem {
+	items := m.items
+	indexes := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		indexes = append(indexes, i)
+	}
 
-		items := m.items
-		targets := make([]string, len(items))
+	// Each term narrows down the matches of the previous one. The order
+	// and matched characters come from the last term.
+	var matches [][]int
+	for n, term := range m.filterTerms() {
+		var targets []string
+		if n == 0 {
+			targets = m.filterValues()[start:end]
+		} else {
+			targets = make([]string, len(indexes))
+			for i, index := range indexes {
+				targets[i] = items[index].FilterValue()
+			}
+		}
 
-		for i, t := range items {
-			targets[i] = t.FilterValue()
+		ranks := m.Filter(term, targets)
+		matched := make([]int, len(ranks))
+		matches = make([][]int, len(ranks))
+		for i, r := range ranks {
+			matched[i] = indexes[r.Index]
+			matches[i] = r.MatchedIndexes
 		}
+		for _, index := range matchKeywords(m, items, term, indexes, matched) {
+			matched = append(matched, index)
+			matches = append(matches, nil)
+		}
+		indexes = matched
+	}
 
-		filterMatches := []filteredItem{}
-		for _, r := range m.Filter(m.FilterInput.Value(), targets) {
-			filterMatches = append(filterMatches, filteredItem{
-				item:    items[r.Index],
-				matches: r.MatchedIndexes,
-			})
+	filterMatches := make([]filteredItem, 0, len(indexes))
+	for i, index := range indexes {
+		filterMatches = append(filterMatches, filteredItem{
+			item:    items[index],
+			index:   index,
+			matches: matches[i],
+		})
+	}
+	return filterMatches
+}
+
+// matchKeywords returns the items among the given indexes which the term
+// matches through their keywords but which aren't already matched, ranked by
+// their best matching keyword. See KeywordItem.
+func matchKeywords(m Model, items []Item, term string, indexes, matched []int) []int {
+	skip := make(map[int]bool, len(matched))
+	for _, index := range matched {
+		skip[index] = true
+	}
+	var keywords []string
+	var owners []int
+	for _, index := range indexes {
+		item, ok := unwrapItem(items[index]).(KeywordItem)
+		if !ok || skip[index] {
+			continue
 		}
+		for _, k := range item.FilterKeywords() {
+			keywords = append(keywords, k)
+			owners = append(owners, index)
+		}
+	}
+	if len(keywords) == 0 {
+		return nil
+	}
 
-		return FilterMatchesMsg(filterMatches)
+	var found []int
+	for _, r := range m.Filter(term, keywords) {
+		if index := owners[r.Index]; !skip[index] {
+			skip[index] = true
+			found = append(found, index)
+		}
 	}
+	return found
 }
 
 func swapItemsInSlice(items []Item, firstIndex, secondIndex int) []Item {
@@ -1219,13 +4850,21 @@ func removeItemFromSlice(i []Item, index int) []Item {
 	return i[:len(i)-1]
 }
 
+// removeFilterMatchFromSlice returns the matches without the match of the item
+// at the given index in the master set of items, if it matched, and with the
+// indexes of later items shifted to account for its removal.
 func removeFilterMatchFromSlice(i []filteredItem, index int) []filteredItem {
-	if index >= len(i) {
-		return i // noop
+	agg := make([]filteredItem, 0, len(i))
+	for _, match := range i {
+		if match.index == index {
+			continue
+		}
+		if match.index > index {
+			match.index--
+		}
+		agg = append(agg, match)
 	}
-	copy(i[index:], i[index+1:])
-	i[len(i)-1] = filteredItem{}
-	return i[:len(i)-1]
+	return agg
 }
 
 func countEnabledBindings(groups [][]key.Binding) (agg int) {
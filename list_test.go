@@ -3,10 +3,17 @@ package list
 import (
 	"fmt"
 	"io"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 type item string
@@ -28,6 +35,45 @@ func (d itemDelegate) Render(w io.Writer, m Model, index int, listItem Item) {
 	fmt.Fprint(w, m.Styles.TitleBar.Render(str))
 }
 
+// namedItem is an item whose filter value is its name, for tests which filter
+// or identify items.
+type namedItem string
+
+func (i namedItem) FilterValue() string { return string(i) }
+
+// plainDelegate renders named items as "N. name" without any styling, one
+// line each.
+type plainDelegate struct{}
+
+func (d plainDelegate) Height() int                          { return 1 }
+func (d plainDelegate) Spacing() int                         { return 0 }
+func (d plainDelegate) Update(msg tea.Msg, m *Model) tea.Cmd { return nil }
+func (d plainDelegate) Render(w io.Writer, m Model, index int, listItem Item) {
+	i, ok := listItem.(namedItem)
+	if !ok {
+		return
+	}
+	fmt.Fprintf(w, "%d. %s", index+1, i)
+}
+
+// collectMsgs runs a command and returns the messages it produces, unwrapping
+// batches.
+func collectMsgs(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	batch, ok := msg.(tea.BatchMsg)
+	if !ok {
+		return []tea.Msg{msg}
+	}
+	var msgs []tea.Msg
+	for _, c := range batch {
+		msgs = append(msgs, collectMsgs(c)...)
+	}
+	return msgs
+}
+
 func TestStatusBarItemName(t *testing.T) {
 	list := New([]Item{item("foo"), item("bar")}, itemDelegate{}, 10, 10)
 	expected := "2 items"
@@ -72,3 +118,3288 @@ func TestCustomStatusBarItemName(t *testing.T) {
 		t.Fatalf("Error: expected view to contain %s", expected)
 	}
 }
+
+func TestStatusBarItemNameFunc(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 10, 10)
+	list.SetStatusBarItemNameFunc(func(count int) string {
+		switch count {
+		case 0:
+			return "results"
+		case 1:
+			return "result"
+		case 2:
+			return "results (two)"
+		}
+		return "results"
+	})
+
+	expected := "2 results (two)"
+	if !strings.Contains(list.statusView(), expected) {
+		t.Fatalf("Error: expected view to contain %s", expected)
+	}
+
+	list.SetItems([]Item{namedItem("foo")})
+	expected = "1 result"
+	if !strings.Contains(list.statusView(), expected) {
+		t.Fatalf("Error: expected view to contain %s", expected)
+	}
+
+	list.SetItems([]Item{})
+	expected = "No results"
+	if !strings.Contains(list.statusView(), expected) {
+		t.Fatalf("Error: expected view to contain %s", expected)
+	}
+}
+
+func TestOnActivate(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 10, 10)
+
+	var (
+		activatedIndex = -1
+		activatedItem  Item
+	)
+	list.OnActivate = func(index int, i Item) tea.Cmd {
+		activatedIndex = index
+		activatedItem = i
+		return nil
+	}
+
+	list.CursorDown()
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if activatedIndex != 1 || activatedItem != namedItem("bar") {
+		t.Fatalf("Error: expected item 1 (bar) to be activated, got %d (%v)", activatedIndex, activatedItem)
+	}
+
+	var inFullHelp bool
+	for _, group := range list.FullHelp() {
+		for _, b := range group {
+			inFullHelp = inFullHelp || b.Help().Desc == "choose"
+		}
+	}
+	if !inFullHelp {
+		t.Fatalf("Error: expected Activate to be in the full help")
+	}
+}
+
+func TestActivateAcceptsFilterWhileFiltering(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 10, 10)
+
+	activated := false
+	list.OnActivate = func(int, Item) tea.Cmd {
+		activated = true
+		return nil
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if activated {
+		t.Fatalf("Error: expected enter not to activate an item while filtering")
+	}
+	if list.FilterState() != FilterApplied {
+		t.Fatalf("Error: expected filter state %s, got %s", FilterApplied, list.FilterState())
+	}
+}
+
+func TestGoToEndWhileFiltered(t *testing.T) {
+	list := New([]Item{namedItem("apple"), namedItem("banana"), namedItem("avocado"), namedItem("cherry")}, plainDelegate{}, 10, 10)
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	list, _ = list.Update(filterItems(list)())
+
+	available := list.AvailableItems()
+	if len(available) != 3 {
+		t.Fatalf("Error: expected 3 filtered items, got %d", len(available))
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+
+	if list.Index() != 2 {
+		t.Fatalf("Error: expected index 2, got %d", list.Index())
+	}
+	if list.SelectedItem() != available[2] {
+		t.Fatalf("Error: expected %v to be selected, got %v", available[2], list.SelectedItem())
+	}
+}
+
+func TestRemoveSelectedItem(t *testing.T) {
+	tests := []struct {
+		name     string
+		selected int
+		index    int
+		item     Item
+	}{
+		{"first", 0, 0, namedItem("bar")},
+		{"middle", 1, 1, namedItem("baz")},
+		{"last", 2, 1, namedItem("bar")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, plainDelegate{}, 10, 10)
+			list.Select(tc.selected)
+			list.RemoveItem(tc.selected)
+
+			if list.Index() != tc.index {
+				t.Fatalf("Error: expected index %d, got %d", tc.index, list.Index())
+			}
+			if list.SelectedItem() != tc.item {
+				t.Fatalf("Error: expected %v to be selected, got %v", tc.item, list.SelectedItem())
+			}
+		})
+	}
+}
+
+func TestRemoveOnlyItem(t *testing.T) {
+	list := New([]Item{namedItem("foo")}, plainDelegate{}, 10, 10)
+	list.RemoveItem(0)
+
+	if list.Index() != -1 {
+		t.Fatalf("Error: expected index -1, got %d", list.Index())
+	}
+	if list.SelectedItem() != nil {
+		t.Fatalf("Error: expected no selected item, got %v", list.SelectedItem())
+	}
+}
+
+func TestSetItemsShrinksSelection(t *testing.T) {
+	items := make([]Item, 10)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, plainDelegate{}, 10, 10)
+	list.Select(8)
+
+	list.SetItems([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")})
+
+	if list.Index() != 2 {
+		t.Fatalf("Error: expected index 2, got %d", list.Index())
+	}
+	if list.SelectedItem() != namedItem("baz") {
+		t.Fatalf("Error: expected baz to be selected, got %v", list.SelectedItem())
+	}
+
+	list.Select(1)
+	list.SetItems([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz"), namedItem("qux")})
+
+	if list.Index() != 1 {
+		t.Fatalf("Error: expected index 1, got %d", list.Index())
+	}
+}
+
+func TestHeader(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 10, 10)
+	list.SetHeader("Name")
+
+	if !strings.Contains(list.View(), "Name") {
+		t.Fatalf("Error: expected view to contain header")
+	}
+
+	list.HeaderFunc = func(m Model) string {
+		return fmt.Sprintf("Total %d", len(m.Items()))
+	}
+	if !strings.Contains(list.View(), "Total 2") {
+		t.Fatalf("Error: expected view to contain header from HeaderFunc")
+	}
+}
+
+func TestFooterPosition(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 40, 20)
+	list.SetFooter("path/to/list")
+
+	view := list.View()
+	footerAt := strings.Index(view, "path/to/list")
+	helpAt := strings.Index(view, "filter")
+	if footerAt < 0 || helpAt < 0 {
+		t.Fatalf("Error: expected view to contain footer and help")
+	}
+	if footerAt > helpAt {
+		t.Fatalf("Error: expected footer to be rendered above help")
+	}
+
+	list.SetFooterPosition(FooterBelowHelp)
+	view = list.View()
+	if strings.Index(view, "path/to/list") < strings.Index(view, "filter") {
+		t.Fatalf("Error: expected footer to be rendered below help")
+	}
+}
+
+func TestInsets(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 0, 0)
+	list.SetSize(30, 20)
+	list.SetInsets(1, 2, 1, 2)
+
+	if list.Width() != 30 || list.Height() != 20 {
+		t.Fatalf("Error: expected size 30x20, got %dx%d", list.Width(), list.Height())
+	}
+
+	view := list.View()
+	if h := lipgloss.Height(view); h != 18 {
+		t.Fatalf("Error: expected view height 18, got %d", h)
+	}
+	if w := lipgloss.Width(view); w > 26 {
+		t.Fatalf("Error: expected view width at most 26, got %d", w)
+	}
+
+	list.SetWidth(list.Width())
+	if list.Height() != 20 {
+		t.Fatalf("Error: expected height to be unchanged, got %d", list.Height())
+	}
+}
+
+type titledItem string
+
+func (i titledItem) FilterValue() string { return string(i) }
+func (i titledItem) Title() string       { return string(i) }
+
+func TestDefaultDelegateRightToLeft(t *testing.T) {
+	d := NewDefaultDelegate()
+	d.SetDirection(RightToLeft)
+	list := New([]Item{titledItem("abcdefghijklmnop"), titledItem("xyz")}, d, 12, 10)
+
+	var b strings.Builder
+	d.Render(&b, list, 0, list.Items()[0])
+	line := b.String()
+	if w := lipgloss.Width(line); w != 12 {
+		t.Fatalf("Error: expected rendered width 12, got %d", w)
+	}
+	if !strings.Contains(line, "…") || !strings.Contains(line, "mnop") || strings.Contains(line, "abc") {
+		t.Fatalf("Error: expected item to be truncated from the left, got %q", line)
+	}
+
+	b.Reset()
+	d.Render(&b, list, 1, list.Items()[1])
+	line = b.String()
+	if w := lipgloss.Width(line); w != 12 {
+		t.Fatalf("Error: expected rendered width 12, got %d", w)
+	}
+	if !strings.HasSuffix(strings.TrimRight(line, " "), "xyz") {
+		t.Fatalf("Error: expected item to be right-aligned, got %q", line)
+	}
+}
+
+func TestTruncateLeft(t *testing.T) {
+	s, matches := truncateLeft("abcdef", 4, "…", []int{0, 4, 5})
+	if s != "…def" {
+		t.Fatalf("Error: expected …def, got %s", s)
+	}
+	if len(matches) != 2 || matches[0] != 2 || matches[1] != 3 {
+		t.Fatalf("Error: expected matches [2 3], got %v", matches)
+	}
+
+	s, _ = truncateLeft("日本語です", 5, "…", nil)
+	if s != "…です" {
+		t.Fatalf("Error: expected …です, got %s", s)
+	}
+}
+
+func TestPlainView(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, plainDelegate{}, 20, 20)
+	list.Select(1)
+
+	expected := "3 items\n  foo\n> bar\n  baz"
+	if v := list.PlainView(); v != expected {
+		t.Fatalf("Error: expected plain view %q, got %q", expected, v)
+	}
+
+	list.SetItems(nil)
+	expected = "No items"
+	if v := list.PlainView(); v != expected {
+		t.Fatalf("Error: expected plain view %q, got %q", expected, v)
+	}
+}
+
+func TestAnnouncement(t *testing.T) {
+	list := New([]Item{namedItem("apple"), namedItem("banana"), namedItem("cherry")}, plainDelegate{}, 20, 20)
+	list.CursorDown()
+
+	expected := "Item 2 of 3 selected: banana"
+	if a := list.Announcement(); a != expected {
+		t.Fatalf("Error: expected announcement %q, got %q", expected, a)
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	list, _ = list.Update(filterItems(list)())
+
+	expected = "Item 1 of 1 selected: cherry; filter applied: 1 match"
+	if a := list.Announcement(); a != expected {
+		t.Fatalf("Error: expected announcement %q, got %q", expected, a)
+	}
+
+	list.SetItems(nil)
+	list.ResetFilter()
+	expected = "No items"
+	if a := list.Announcement(); a != expected {
+		t.Fatalf("Error: expected announcement %q, got %q", expected, a)
+	}
+}
+
+func TestReset(t *testing.T) {
+	items := []Item{namedItem("apple"), namedItem("banana"), namedItem("cherry")}
+	list := New(items, plainDelegate{}, 20, 5)
+	initial := New(items, plainDelegate{}, 20, 5)
+
+	list.Select(2)
+	list.firstItemIndexInView, list.lastItemIndexInView = 2, 2
+	list.StartSpinner()
+	list.NewStatusMessage("hello")
+	list.Help.ShowAll = true
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+
+	list.Reset()
+
+	if list.Index() != initial.Index() {
+		t.Fatalf("Error: expected index %d, got %d", initial.Index(), list.Index())
+	}
+	if list.FilterState() != initial.FilterState() || list.FilterValue() != initial.FilterValue() {
+		t.Fatalf("Error: expected filter to be reset, got %s %q", list.FilterState(), list.FilterValue())
+	}
+	if list.filteredItems != nil {
+		t.Fatalf("Error: expected filtered items to be cleared")
+	}
+	if list.showSpinner {
+		t.Fatalf("Error: expected spinner to be stopped")
+	}
+	if list.statusMessage != "" {
+		t.Fatalf("Error: expected status message to be cleared, got %q", list.statusMessage)
+	}
+	if list.Help.ShowAll {
+		t.Fatalf("Error: expected full help to be closed")
+	}
+	if list.firstItemIndexInView != 0 || list.lastItemIndexInView != 0 {
+		t.Fatalf("Error: expected viewport to be scrolled to the top")
+	}
+	if list.KeyMap.Filter.Enabled() != initial.KeyMap.Filter.Enabled() ||
+		list.KeyMap.CancelWhileFiltering.Enabled() != initial.KeyMap.CancelWhileFiltering.Enabled() {
+		t.Fatalf("Error: expected keybindings to match their initial state")
+	}
+	if len(list.Items()) != len(items) {
+		t.Fatalf("Error: expected items to be kept")
+	}
+}
+
+func TestResetWhileSearching(t *testing.T) {
+	items := []Item{namedItem("apple"), namedItem("banana"), namedItem("cherry")}
+	list := New(items, plainDelegate{}, 20, 10)
+	initial := New(items, plainDelegate{}, 20, 10)
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyCtrlF})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("ch")})
+	if !list.searching || list.Index() != 2 {
+		t.Fatalf("Error: expected the search to select cherry, got %d", list.Index())
+	}
+	list.pendingKeys = []string{"g"}
+	list.confirmingQuit = true
+	list.hoverIndex, list.dragFrom, list.dragTo = 1, 0, 1
+
+	list.Reset()
+
+	if list.searching || list.SearchTerm() != "" || list.Index() != 0 {
+		t.Fatalf("Error: expected the search to be cleared, got %q and %d", list.SearchTerm(), list.Index())
+	}
+	if list.confirmingQuit || list.pendingKeys != nil || list.IsHovered(1) || list.dragFrom != -1 || list.dragTo != -1 {
+		t.Fatalf("Error: expected the transient state to be cleared")
+	}
+	if list.KeyMap.Filter.Enabled() != initial.KeyMap.Filter.Enabled() ||
+		list.KeyMap.CursorDown.Enabled() != initial.KeyMap.CursorDown.Enabled() {
+		t.Fatalf("Error: expected keybindings to match their initial state")
+	}
+
+	// Typing goes to the list again rather than the search.
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if list.Index() != 1 || list.SearchTerm() != "" {
+		t.Fatalf("Error: expected down to move the cursor, got %d and %q", list.Index(), list.SearchTerm())
+	}
+}
+
+func TestClone(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, plainDelegate{}, 20, 20)
+	list.NewStatusMessage("hello")
+
+	clone := list.Clone()
+	clone.SetItem(0, namedItem("qux"))
+	clone.RemoveItem(1)
+
+	if list.Items()[0] != namedItem("foo") || len(list.Items()) != 3 {
+		t.Fatalf("Error: expected original items to be unchanged, got %v", list.Items())
+	}
+	if clone.statusMessage != "" || clone.statusMessageTimer != nil {
+		t.Fatalf("Error: expected clone not to share the status message")
+	}
+	if list.statusMessage != "hello" {
+		t.Fatalf("Error: expected original status message to be kept")
+	}
+}
+
+func TestMoveItemAtBoundaries(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, plainDelegate{}, 20, 20)
+
+	list.MoveItemUp(0)
+	if list.Index() != 0 || list.Items()[0] != namedItem("foo") || list.Items()[1] != namedItem("bar") {
+		t.Fatalf("Error: expected moving the first item up to be a no-op")
+	}
+
+	list.Select(2)
+	list.MoveItemDown(2)
+	if list.Index() != 2 || list.Items()[2] != namedItem("baz") || list.Items()[1] != namedItem("bar") {
+		t.Fatalf("Error: expected moving the last item down to be a no-op")
+	}
+
+	list.MoveItemUp(2)
+	if list.Index() != 1 || list.Items()[1] != namedItem("baz") || list.Items()[2] != namedItem("bar") {
+		t.Fatalf("Error: expected baz to move up, got %v", list.Items())
+	}
+}
+
+func TestMatchesForItemAfterClearingFilter(t *testing.T) {
+	list := New([]Item{namedItem("apple"), namedItem("banana")}, plainDelegate{}, 20, 20)
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	msg := filterItems(list)()
+	list, _ = list.Update(msg)
+
+	if list.MatchesForItem(0) == nil {
+		t.Fatalf("Error: expected matches while the filter is applied")
+	}
+
+	list.ResetFilter()
+
+	// A stale filter result arriving after the filter was cleared.
+	list, _ = list.Update(msg)
+
+	if matches := list.MatchesForItem(0); matches != nil {
+		t.Fatalf("Error: expected no matches after clearing the filter, got %v", matches)
+	}
+}
+
+func TestInlineFilter(t *testing.T) {
+	list := New([]Item{namedItem("apple"), namedItem("banana"), namedItem("blueberry")}, plainDelegate{}, 20, 20)
+	list.SetInlineFilter(true)
+	if list.KeyMap.MoveUp.Enabled() || list.KeyMap.MoveDown.Enabled() {
+		t.Fatalf("Error: expected moving items to be disabled while filtering inline")
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	list, _ = list.Update(filterItems(list)())
+
+	if list.FilterState() != FilterApplied {
+		t.Fatalf("Error: expected filter state %s, got %s", FilterApplied, list.FilterState())
+	}
+	if n := len(list.AvailableItems()); n != 2 {
+		t.Fatalf("Error: expected 2 matching items, got %d", n)
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if list.Index() != 1 {
+		t.Fatalf("Error: expected arrow keys to navigate, got index %d", list.Index())
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	if list.FilterValue() != "bj" {
+		t.Fatalf("Error: expected printable keys to be typed, got %q", list.FilterValue())
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	if list.FilterState() != Unfiltered || len(list.AvailableItems()) != 3 {
+		t.Fatalf("Error: expected clearing the input to remove the filter")
+	}
+}
+
+func TestApplyFilter(t *testing.T) {
+	list := New([]Item{namedItem("apple"), namedItem("banana"), namedItem("cherry")}, plainDelegate{}, 20, 20)
+	list.Select(2)
+
+	cmd := list.ApplyFilter("an")
+	list, _ = list.Update(cmd())
+
+	if list.FilterState() != FilterApplied {
+		t.Fatalf("Error: expected filter state %s, got %s", FilterApplied, list.FilterState())
+	}
+	if list.FilterValue() != "an" {
+		t.Fatalf("Error: expected filter value %q, got %q", "an", list.FilterValue())
+	}
+	if list.Index() != 0 || list.SelectedItem() != namedItem("banana") {
+		t.Fatalf("Error: expected banana to be selected, got %v", list.SelectedItem())
+	}
+	if !list.KeyMap.ClearFilter.Enabled() || list.KeyMap.CancelWhileFiltering.Enabled() {
+		t.Fatalf("Error: expected keybindings to reflect the applied filter")
+	}
+
+	if cmd := list.ApplyFilter(""); cmd != nil {
+		t.Fatalf("Error: expected no command when clearing the filter")
+	}
+	if list.FilterState() != Unfiltered || len(list.AvailableItems()) != 3 {
+		t.Fatalf("Error: expected an empty term to clear the filter")
+	}
+
+	// A term matching nothing is cleared once the matches arrive, just like
+	// when the user accepts it.
+	typed := list
+	typed = typed.UpdateSync(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	typed = typed.UpdateSync(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("zz")})
+	typed = typed.UpdateSync(tea.KeyMsg{Type: tea.KeyEnter})
+	list, _ = list.Update(list.ApplyFilter("zz")())
+	if list.FilterState() != Unfiltered || len(list.AvailableItems()) != 3 {
+		t.Fatalf("Error: expected a term without matches to clear the filter, got %s", list.FilterState())
+	}
+	if typed.FilterState() != list.FilterState() || typed.FilterValue() != list.FilterValue() {
+		t.Fatalf("Error: expected the same state as accepting the term, got %s %q and %s %q",
+			list.FilterState(), list.FilterValue(), typed.FilterState(), typed.FilterValue())
+	}
+}
+
+func TestFilterTrimsWhitespace(t *testing.T) {
+	list := New([]Item{namedItem("foo bar"), namedItem("baz")}, plainDelegate{}, 20, 20)
+
+	var terms []string
+	list.Filter = func(term string, targets []string) []Rank {
+		terms = append(terms, term)
+		return DefaultFilter(term, targets)
+	}
+
+	list.ApplyFilter("  foo ")
+	list, _ = list.Update(filterItems(list)())
+	if len(terms) != 1 || terms[0] != "foo" {
+		t.Fatalf("Error: expected the filter term to be trimmed, got %q", terms)
+	}
+}
+
+func TestWhitespaceFilterResetsToUnfiltered(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 20, 20)
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	list, _ = list.Update(filterItems(list)())
+
+	if n := len(list.AvailableItems()); n != 2 {
+		t.Fatalf("Error: expected whitespace to match every item, got %d", n)
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if list.FilterState() != Unfiltered {
+		t.Fatalf("Error: expected filter state %s, got %s", Unfiltered, list.FilterState())
+	}
+}
+
+func TestAccentInsensitiveFilter(t *testing.T) {
+	ranks := AccentInsensitiveFilter("cafe", []string{"tea", "café", "café au lait"})
+	if len(ranks) != 2 {
+		t.Fatalf("Error: expected 2 matches, got %d", len(ranks))
+	}
+
+	expected := map[int][]int{
+		// Precomposed é is a single rune.
+		1: {0, 1, 2, 3},
+		// The combining acute accent is highlighted along with the e.
+		2: {0, 1, 2, 3, 4},
+	}
+	for _, r := range ranks {
+		want, ok := expected[r.Index]
+		if !ok {
+			t.Fatalf("Error: unexpected match at index %d", r.Index)
+		}
+		if fmt.Sprint(r.MatchedIndexes) != fmt.Sprint(want) {
+			t.Fatalf("Error: expected matched indexes %v for target %d, got %v", want, r.Index, r.MatchedIndexes)
+		}
+	}
+}
+
+func TestAccentInsensitiveFilterAccentedTerm(t *testing.T) {
+	ranks := AccentInsensitiveFilter("naïve", []string{"naive", "other"})
+	if len(ranks) != 1 || ranks[0].Index != 0 {
+		t.Fatalf("Error: expected an accented term to match an unaccented target, got %v", ranks)
+	}
+}
+
+func TestAutoHeight(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 20, 20)
+	list.SetShowTitle(false)
+	list.SetShowFilter(false)
+	list.SetShowStatusBar(false)
+	list.SetShowHelp(false)
+
+	if h := lipgloss.Height(list.View()); h != 20 {
+		t.Fatalf("Error: expected view height 20, got %d", h)
+	}
+
+	list.SetAutoHeight(true)
+	expected := lipgloss.Height(list.populatedView())
+	if h := lipgloss.Height(list.View()); h != expected {
+		t.Fatalf("Error: expected view height %d, got %d", expected, h)
+	}
+
+	items := make([]Item, 50)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	list.SetItems(items)
+	if h := lipgloss.Height(list.View()); h != 20 {
+		t.Fatalf("Error: expected view height to be capped at 20, got %d", h)
+	}
+}
+
+func TestFilterCharCount(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 40, 20)
+	list.SetShowFilterCharCount(true)
+	list.SetFilterCharLimit(3)
+
+	inputWidth := list.FilterInput.Width
+	list.SetShowFilterCharCount(false)
+	if list.FilterInput.Width <= inputWidth {
+		t.Fatalf("Error: expected the char count to take up space in the title bar")
+	}
+	list.SetShowFilterCharCount(true)
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	if !strings.Contains(list.titleView(), "1/3") {
+		t.Fatalf("Error: expected title view to contain the char count")
+	}
+
+	for _, r := range "ooo" {
+		list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if list.FilterValue() != "foo" {
+		t.Fatalf("Error: expected the filter to be limited to 3 characters, got %q", list.FilterValue())
+	}
+	if !strings.Contains(list.titleView(), "3/3") {
+		t.Fatalf("Error: expected title view to contain the char count")
+	}
+}
+
+func TestOnFilterLimit(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 40, 20)
+	list.SetFilterCharLimit(2)
+
+	hits := 0
+	list.OnFilterLimit = func() tea.Cmd {
+		hits++
+		return nil
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	for _, r := range "fo" {
+		list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if hits != 0 {
+		t.Fatalf("Error: expected OnFilterLimit not to be called below the limit")
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	if hits != 1 {
+		t.Fatalf("Error: expected OnFilterLimit to be called once, got %d", hits)
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	if hits != 1 {
+		t.Fatalf("Error: expected OnFilterLimit not to be called for non-printable keys")
+	}
+}
+
+func TestGoToStartSequence(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz"), namedItem("qux")}, plainDelegate{}, 20, 20)
+	list.KeyMap.GoToStart = key.NewBinding(key.WithKeys("home"))
+	list.Select(3)
+
+	g := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g")}
+	list, _ = list.Update(g)
+	if list.Index() != 3 {
+		t.Fatalf("Error: expected a single g not to move the cursor, got index %d", list.Index())
+	}
+	list, _ = list.Update(g)
+	if list.Index() != 0 {
+		t.Fatalf("Error: expected gg to go to the start, got index %d", list.Index())
+	}
+
+	// A non-matching key clears the pending sequence.
+	list, _ = list.Update(g)
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	list, _ = list.Update(g)
+	if list.Index() != 2 {
+		t.Fatalf("Error: expected g j j g not to go to the start, got index %d", list.Index())
+	}
+
+	// A timeout clears the pending sequence.
+	list, _ = list.Update(keySequenceTimeoutMsg(list.keySequenceID))
+	list, _ = list.Update(g)
+	if list.Index() != 2 {
+		t.Fatalf("Error: expected the sequence to time out, got index %d", list.Index())
+	}
+}
+
+func TestScrollAnimation(t *testing.T) {
+	items := make([]Item, 20)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, plainDelegate{}, 20, 5)
+	list.SetShowTitle(false)
+	list.SetShowFilter(false)
+	list.SetShowStatusBar(false)
+	list.SetShowHelp(false)
+	list.SetScrollAnimation(4, time.Millisecond)
+	var scrolls []int
+	list.OnScroll = func(first, _ int) {
+		scrolls = append(scrolls, first)
+	}
+
+	// The viewport scrolls towards the end while the selection stays put,
+	// then the last item is selected once it's in view.
+	list, cmd := list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	for {
+		if list.Index() != 0 {
+			t.Fatalf("Error: expected the selection to stay put while scrolling, got %d", list.Index())
+		}
+		var next tea.Msg
+		for _, msg := range collectMsgs(cmd) {
+			if m, ok := msg.(scrollAnimationMsg); ok {
+				next = m
+			}
+		}
+		if next == nil {
+			break
+		}
+		list, cmd = list.Update(next)
+		if !list.scrolling {
+			break
+		}
+	}
+	if list.Index() != 19 || fmt.Sprint(scrolls) != "[4 8 12 15]" {
+		t.Fatalf("Error: expected to scroll through [4 8 12 15] and select 19, got %v and %d", scrolls, list.Index())
+	}
+
+	// Paging animates too.
+	list, cmd = list.Update(tea.KeyMsg{Type: tea.KeyPgUp})
+	for _, msg := range collectMsgs(cmd) {
+		list, _ = list.Update(msg)
+	}
+	if list.Index() != 14 {
+		t.Fatalf("Error: expected paging up to select 14, got %d", list.Index())
+	}
+
+	// Pressing a key finishes the animation before the key applies.
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyHome})
+	if !list.scrolling || list.Index() != 14 {
+		t.Fatalf("Error: expected going to the start to scroll")
+	}
+	pending := scrollAnimationMsg{id: list.scrollAnimationID}
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if list.Index() != 1 {
+		t.Fatalf("Error: expected the key to apply after the jump, got %d", list.Index())
+	}
+	list, _ = list.Update(pending)
+	if list.Index() != 1 {
+		t.Fatalf("Error: expected a finished animation not to move the cursor")
+	}
+
+	// Resetting stops the animation without selecting its target.
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	pending = scrollAnimationMsg{id: list.scrollAnimationID}
+	list.Reset()
+	list, _ = list.Update(pending)
+	if list.scrolling || list.Index() != 0 || list.firstItemIndexInView != 0 {
+		t.Fatalf("Error: expected a reset to stop the animation, got %d", list.Index())
+	}
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if list.Index() != 1 {
+		t.Fatalf("Error: expected the next key to apply from the start, got %d", list.Index())
+	}
+}
+
+func TestInputLocked(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, plainDelegate{}, 20, 20)
+	list.SetInputLocked(true)
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyDown})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	if list.Index() != 0 || list.FilterState() != Unfiltered {
+		t.Fatalf("Error: expected keys to be ignored while input is locked")
+	}
+
+	_, cmd := list.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if cmd == nil {
+		t.Fatalf("Error: expected force quit to work while input is locked")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Fatalf("Error: expected force quit to return tea.Quit")
+	}
+
+	list.SetInputLocked(false)
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if list.Index() != 1 {
+		t.Fatalf("Error: expected keys to be handled once input is unlocked")
+	}
+}
+
+type itemUpdaterDelegate struct {
+	plainDelegate
+	updates *[]int
+}
+
+func (d itemUpdaterDelegate) UpdateItem(msg tea.Msg, m *Model, index int) tea.Cmd {
+	*d.updates = append(*d.updates, index)
+	m.SetItem(index, namedItem(fmt.Sprint(msg)))
+	return nil
+}
+
+func TestItemMsg(t *testing.T) {
+	var updates []int
+	d := itemUpdaterDelegate{updates: &updates}
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, d, 20, 20)
+
+	list, _ = list.Update(ItemMsg{Index: 1, Msg: "baz"})
+	list, _ = list.Update(ItemMsg{Index: 5, Msg: "qux"})
+
+	if fmt.Sprint(updates) != "[1]" {
+		t.Fatalf("Error: expected only item 1 to be updated, got %v", updates)
+	}
+	if list.Items()[1] != namedItem("baz") {
+		t.Fatalf("Error: expected item 1 to be updated, got %v", list.Items()[1])
+	}
+}
+
+type selectionDelegate struct {
+	plainDelegate
+	events *[]string
+}
+
+func (d selectionDelegate) OnSelect(m *Model, index int) tea.Cmd {
+	*d.events = append(*d.events, fmt.Sprintf("select %d", index))
+	return nil
+}
+
+func (d selectionDelegate) OnDeselect(m *Model, index int) tea.Cmd {
+	*d.events = append(*d.events, fmt.Sprintf("deselect %d", index))
+	return nil
+}
+
+func TestSelectionDelegate(t *testing.T) {
+	var events []string
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, selectionDelegate{events: &events}, 20, 20)
+
+	list, _ = list.Update(nil)
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyDown})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyDown})
+
+	expected := "[select 0 deselect 0 select 1]"
+	if fmt.Sprint(events) != expected {
+		t.Fatalf("Error: expected events %s, got %v", expected, events)
+	}
+}
+
+func TestDefaultDelegateShowIndex(t *testing.T) {
+	items := make([]Item, 12)
+	for i := range items {
+		items[i] = titledItem(fmt.Sprintf("item %d", i))
+	}
+	d := NewDefaultDelegate()
+	d.ShowIndex = true
+	list := New(items, d, 12, 20)
+
+	var b strings.Builder
+	d.Render(&b, list, 2, items[2])
+	line := b.String()
+	if !strings.Contains(line, " 3 item") {
+		t.Fatalf("Error: expected a padded index prefix, got %q", line)
+	}
+	if w := lipgloss.Width(line); w > 12 {
+		t.Fatalf("Error: expected the line to fit in 12 cells, got %d", w)
+	}
+
+	b.Reset()
+	d.Render(&b, list, 11, items[11])
+	if !strings.Contains(b.String(), "12 item") {
+		t.Fatalf("Error: expected index 12, got %q", b.String())
+	}
+
+	// When filtered, the index is the position among the filtered items.
+	list.ApplyFilter("item 11")
+	list, _ = list.Update(filterItems(list)())
+	b.Reset()
+	d.Render(&b, list, 0, list.AvailableItems()[0])
+	if !strings.Contains(b.String(), "1 ") {
+		t.Fatalf("Error: expected index 1 for the first filtered item, got %q", b.String())
+	}
+}
+
+func TestDefaultDelegateZebraStripe(t *testing.T) {
+	items := []Item{titledItem("foo"), titledItem("bar"), titledItem("baz")}
+	d := NewDefaultDelegate()
+	d.ZebraStripe = true
+	list := New(items, d, 20, 20)
+
+	for i, it := range items {
+		var b strings.Builder
+		d.Render(&b, list, i, it)
+		line := b.String()
+
+		// The selected row isn't striped and keeps its own width.
+		if i == list.Index() {
+			if lipgloss.Width(line) == 20 {
+				t.Fatalf("Error: expected the selected row not to be striped")
+			}
+			continue
+		}
+		if w := lipgloss.Width(line); w != 20 {
+			t.Fatalf("Error: expected striped row %d to fill the width, got %d", i, w)
+		}
+	}
+}
+
+func TestDefaultDelegateFullWidthSelection(t *testing.T) {
+	items := []Item{titledItem("foo"), titledItem("bar")}
+	d := NewDefaultDelegate()
+	list := New(items, d, 20, 20)
+
+	var b strings.Builder
+	d.Render(&b, list, 0, items[0])
+	if w := lipgloss.Width(b.String()); w == 20 {
+		t.Fatalf("Error: expected the selected row not to fill the width by default")
+	}
+
+	d.FullWidthSelection = true
+	b.Reset()
+	d.Render(&b, list, 0, items[0])
+	if w := lipgloss.Width(b.String()); w != 20 {
+		t.Fatalf("Error: expected the selected row to fill the width, got %d", w)
+	}
+
+	b.Reset()
+	d.Render(&b, list, 1, items[1])
+	if w := lipgloss.Width(b.String()); w == 20 {
+		t.Fatalf("Error: expected unselected rows not to fill the width")
+	}
+}
+
+type styledItem struct {
+	titledItem
+	styles *DefaultItemStyles
+}
+
+func (i styledItem) Styles() *DefaultItemStyles { return i.styles }
+
+func TestDefaultDelegateItemStyles(t *testing.T) {
+	d := NewDefaultDelegate()
+	list := New(nil, d, 20, 20)
+
+	// Only NormalTitle is overridden, SelectedTitle falls back to the
+	// delegate's style.
+	override := &DefaultItemStyles{
+		NormalTitle: lipgloss.NewStyle().PaddingLeft(5),
+	}
+	list.SetItems([]Item{
+		styledItem{titledItem("foo"), override},
+		styledItem{titledItem("bar"), override},
+		styledItem{titledItem("baz"), nil},
+	})
+
+	render := func(index int) string {
+		var b strings.Builder
+		d.Render(&b, list, index, list.Items()[index])
+		return b.String()
+	}
+
+	if line := render(1); !strings.HasPrefix(line, "     bar") {
+		t.Fatalf("Error: expected the item's style to be used, got %q", line)
+	}
+	if line := render(0); !strings.HasPrefix(line, "│ foo") {
+		t.Fatalf("Error: expected the delegate's selected style to be used, got %q", line)
+	}
+	if line := render(2); !strings.HasPrefix(line, "  baz") {
+		t.Fatalf("Error: expected the delegate's style to be used, got %q", line)
+	}
+
+	// New delegate styles are picked up by the items' fallbacks.
+	styles := NewDefaultItemStyles()
+	styles.SelectedTitle = lipgloss.NewStyle().PaddingLeft(3)
+	d.SetStyles(styles)
+	if line := render(0); !strings.HasPrefix(line, "   foo") {
+		t.Fatalf("Error: expected the delegate's new selected style to be used, got %q", line)
+	}
+	if line := render(1); !strings.HasPrefix(line, "     bar") {
+		t.Fatalf("Error: expected the item's style to be kept, got %q", line)
+	}
+}
+
+type fakeClipboard struct {
+	text string
+}
+
+func (c *fakeClipboard) WriteAll(text string) error {
+	c.text = text
+	return nil
+}
+
+func TestCopyFilter(t *testing.T) {
+	clipboard := &fakeClipboard{}
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 10, 10)
+	list.Clipboard = clipboard
+	list.KeyMap.CopyFilter.SetEnabled(true)
+
+	copyKey := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")}
+
+	list, _ = list.Update(copyKey)
+	if clipboard.text != "" || list.statusMessage != "" {
+		t.Fatalf("Error: expected nothing to be copied when unfiltered")
+	}
+
+	list.ApplyFilter(" fo ")
+	list, _ = list.Update(filterItems(list)())
+	list, _ = list.Update(copyKey)
+	if clipboard.text != "fo" {
+		t.Fatalf("Error: expected %q to be copied, got %q", "fo", clipboard.text)
+	}
+	if !strings.Contains(list.statusMessage, "fo") {
+		t.Fatalf("Error: expected a status message, got %q", list.statusMessage)
+	}
+	list.hideStatusMessage()
+}
+
+func TestOnScroll(t *testing.T) {
+	items := make([]Item, 10)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, plainDelegate{}, 20, 3)
+	list.SetShowTitle(false)
+	list.SetShowFilter(false)
+	list.SetShowStatusBar(false)
+	list.SetShowHelp(false)
+
+	var calls [][2]int
+	list.OnScroll = func(first, last int) {
+		calls = append(calls, [2]int{first, last})
+	}
+
+	down := tea.KeyMsg{Type: tea.KeyDown}
+	for i := 0; i < 4; i++ {
+		list, _ = list.Update(down)
+		_ = list.View()
+	}
+
+	// The first update establishes the viewport, then only the moves past
+	// the bottom of the viewport scroll it.
+	expected := [][2]int{{0, 2}, {1, 3}, {2, 4}}
+	if fmt.Sprint(calls) != fmt.Sprint(expected) {
+		t.Fatalf("Error: expected OnScroll calls %v, got %v", expected, calls)
+	}
+}
+
+func TestMinHeight(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 20, 50)
+
+	full := list.MinHeight()
+	list.SetShowHelp(false)
+	list.SetShowStatusBar(false)
+	if h := list.MinHeight(); h >= full {
+		t.Fatalf("Error: expected hiding sections to lower the minimum height from %d, got %d", full, h)
+	}
+
+	list.SetHeight(list.MinHeight())
+	view := list.View()
+	if h := lipgloss.Height(view); h != list.MinHeight() {
+		t.Fatalf("Error: expected the view to be %d lines tall, got %d:\n%s", list.MinHeight(), h, view)
+	}
+	if !strings.Contains(view, "1. foo") || strings.Contains(view, "2. bar") {
+		t.Fatalf("Error: expected exactly one item to be shown:\n%s", view)
+	}
+}
+
+func TestOverflowing(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 20, 50)
+	if list.Overflowing() {
+		t.Fatalf("Error: expected the list not to overflow")
+	}
+
+	list.SetHeight(list.MinHeight() - 1)
+	if !list.Overflowing() {
+		t.Fatalf("Error: expected the list to overflow")
+	}
+	if view := list.View(); strings.Contains(view, "foo") {
+		t.Fatalf("Error: expected no items to be rendered:\n%s", view)
+	}
+}
+
+func TestTitleBadge(t *testing.T) {
+	list := New([]Item{namedItem("foo")}, plainDelegate{}, 30, 10)
+	list.Title = "Inbox"
+	list.SetTitleBadge("12")
+
+	title := list.titleView()
+	if !strings.Contains(title, "Inbox") || !strings.Contains(title, "12") {
+		t.Fatalf("Error: expected the title and badge to be rendered, got %q", title)
+	}
+
+	list.SetWidth(10)
+	title = list.titleView()
+	if !strings.Contains(title, "Inbox") {
+		t.Fatalf("Error: expected the title to stay visible, got %q", title)
+	}
+	if w := lipgloss.Width(title); w > 10 {
+		t.Fatalf("Error: expected the title bar to fit in 10 cells, got %d", w)
+	}
+}
+
+func TestShowItemPosition(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, plainDelegate{}, 40, 10)
+	if strings.Contains(list.statusView(), "1/3") {
+		t.Fatalf("Error: expected the position to be hidden by default")
+	}
+
+	list.SetShowItemPosition(true)
+	list.Select(1)
+	if status := list.statusView(); !strings.Contains(status, "2/3") {
+		t.Fatalf("Error: expected the position to be shown, got %q", status)
+	}
+
+	list.ApplyFilter("ba")
+	list, _ = list.Update(filterItems(list)())
+	list.Select(1)
+	if status := list.statusView(); !strings.Contains(status, "2/2") {
+		t.Fatalf("Error: expected the filtered position to be shown, got %q", status)
+	}
+}
+
+func TestToggleFilter(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 10, 10)
+
+	list.ToggleFilter()
+	if list.FilterState() != Filtering {
+		t.Fatalf("Error: expected %s, got %s", Filtering, list.FilterState())
+	}
+
+	list.FilterInput.SetValue("fo")
+	list, _ = list.Update(filterItems(list)())
+	list.ToggleFilter()
+	if list.FilterState() != FilterApplied {
+		t.Fatalf("Error: expected %s, got %s", FilterApplied, list.FilterState())
+	}
+	if len(list.AvailableItems()) != 1 {
+		t.Fatalf("Error: expected 1 available item, got %d", len(list.AvailableItems()))
+	}
+
+	list.ToggleFilter()
+	if list.FilterState() != Unfiltered || list.FilterValue() != "" {
+		t.Fatalf("Error: expected the filter to be cleared, got %s %q", list.FilterState(), list.FilterValue())
+	}
+}
+
+func TestMaxVisibleMatches(t *testing.T) {
+	items := make([]Item, 100)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, plainDelegate{}, 40, 10)
+	list.MaxVisibleMatches = 10
+
+	if n := len(list.AvailableItems()); n != 100 {
+		t.Fatalf("Error: expected the cap not to apply when unfiltered, got %d items", n)
+	}
+
+	list.ApplyFilter("item")
+	list, _ = list.Update(filterItems(list)())
+	if n := len(list.AvailableItems()); n != 10 {
+		t.Fatalf("Error: expected 10 matches to be shown, got %d", n)
+	}
+	if status := list.statusView(); !strings.Contains(status, "showing 10 of 100 matches") {
+		t.Fatalf("Error: expected the status bar to mention the cap, got %q", status)
+	}
+	if status := list.statusView(); strings.Contains(status, "filtered") {
+		t.Fatalf("Error: expected capped matches not to count as filtered, got %q", status)
+	}
+}
+
+func TestJumpHistory(t *testing.T) {
+	items := make([]Item, 10)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, plainDelegate{}, 20, 20)
+
+	back := tea.KeyMsg{Type: tea.KeyCtrlO}
+	forward := tea.KeyMsg{Type: tea.KeyTab}
+
+	list.Select(5)
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyEnd})
+
+	list, _ = list.Update(back)
+	if list.Index() != 5 {
+		t.Fatalf("Error: expected to jump back to 5, got %d", list.Index())
+	}
+	list, _ = list.Update(back)
+	if list.Index() != 0 {
+		t.Fatalf("Error: expected to jump back to 0, got %d", list.Index())
+	}
+	list, _ = list.Update(forward)
+	if list.Index() != 5 {
+		t.Fatalf("Error: expected to jump forward to 5, got %d", list.Index())
+	}
+
+	// Removing an item before an entry shifts it, removing the item an
+	// entry points at drops the entry. The selection stays at index 5, which
+	// is now "item 6", so jumping back first goes to "item 5".
+	list.RemoveItem(2)
+	list.RemoveItem(8)
+	list, _ = list.Update(back)
+	if list.SelectedItem() != namedItem("item 5") {
+		t.Fatalf("Error: expected to jump back to %q, got %v", "item 5", list.SelectedItem())
+	}
+	list, _ = list.Update(back)
+	if list.SelectedItem() != namedItem("item 0") {
+		t.Fatalf("Error: expected to jump back to %q, got %v", "item 0", list.SelectedItem())
+	}
+}
+
+func TestTabBindingsExclusive(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 20, 20)
+	list.SetShowFilterCompletion(true)
+
+	check := func(state string) {
+		t.Helper()
+		if list.KeyMap.JumpForward.Enabled() && list.KeyMap.AcceptCompletion.Enabled() {
+			t.Fatalf("Error: expected tab to be bound once while %s", state)
+		}
+	}
+	check("browsing")
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	check("filtering")
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	check("filtered")
+	if help := list.KeyMap.JumpForward.Help().Key; help != "tab" {
+		t.Fatalf("Error: expected the help to show tab, got %q", help)
+	}
+}
+
+func TestDefaultDelegateSeparator(t *testing.T) {
+	d := NewDefaultDelegate()
+	d.SetSpacing(0)
+	d.Separator = "─"
+	if d.Spacing() != 1 {
+		t.Fatalf("Error: expected the separator to count as spacing, got %d", d.Spacing())
+	}
+
+	list := New([]Item{titledItem("foo"), titledItem("bar")}, d, 10, 20)
+	lines := strings.Split(list.populatedView(), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Error: expected 3 lines, got %d: %q", len(lines), lines)
+	}
+	if lines[1] != strings.Repeat("─", 10) {
+		t.Fatalf("Error: expected a full width separator, got %q", lines[1])
+	}
+
+	d.Separator = ""
+	d.SetSpacing(1)
+	list.SetDelegate(d)
+	lines = strings.Split(list.populatedView(), "\n")
+	if len(lines) != 3 || lines[1] != "" {
+		t.Fatalf("Error: expected blank spacing without a separator, got %q", lines)
+	}
+}
+
+func TestPushPopFilter(t *testing.T) {
+	list := New([]Item{
+		namedItem("red apple"),
+		namedItem("green apple"),
+		namedItem("red pepper"),
+	}, plainDelegate{}, 60, 10)
+
+	list, _ = list.Update(list.PushFilter("red")())
+	if n := len(list.AvailableItems()); n != 2 {
+		t.Fatalf("Error: expected 2 items matching %q, got %d", "red", n)
+	}
+
+	list, _ = list.Update(list.PushFilter("apple")())
+	if n := len(list.AvailableItems()); n != 1 || list.SelectedItem() != namedItem("red apple") {
+		t.Fatalf("Error: expected only %q to match, got %v", "red apple", list.AvailableItems())
+	}
+	if status := list.statusView(); !strings.Contains(status, "“red” › “apple”") {
+		t.Fatalf("Error: expected a breadcrumb in the status bar, got %q", status)
+	}
+
+	list, cmd := list.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	for _, msg := range collectMsgs(cmd) {
+		list, _ = list.Update(msg)
+	}
+	if n := len(list.AvailableItems()); n != 2 {
+		t.Fatalf("Error: expected popping a filter to widen the matches, got %d", n)
+	}
+
+	list.PopFilter()
+	if list.FilterState() != Unfiltered {
+		t.Fatalf("Error: expected popping the last filter to clear it, got %s", list.FilterState())
+	}
+}
+
+func TestMultiTermFilter(t *testing.T) {
+	targets := []string{"foo", "bar", "foo and bar", "bar of foo"}
+	ranks := MultiTermFilter("foo  bar", targets)
+
+	var matched []string
+	for _, r := range ranks {
+		matched = append(matched, targets[r.Index])
+	}
+	if len(ranks) != 2 {
+		t.Fatalf("Error: expected only targets containing both terms to match, got %q", matched)
+	}
+
+	for _, r := range ranks {
+		if r.Index != 2 {
+			continue
+		}
+		expected := []int{0, 1, 2, 8, 9, 10}
+		if fmt.Sprint(r.MatchedIndexes) != fmt.Sprint(expected) {
+			t.Fatalf("Error: expected merged matches %v, got %v", expected, r.MatchedIndexes)
+		}
+	}
+
+	if ranks := MultiTermFilter("   ", targets); len(ranks) != 0 {
+		t.Fatalf("Error: expected no matches for an empty term, got %d", len(ranks))
+	}
+}
+
+func TestExclusionFilter(t *testing.T) {
+	targets := []string{"error: disk full", "debug error: retrying", "info: started"}
+
+	tests := []struct {
+		term     string
+		expected []int
+	}{
+		{"error !debug", []int{0}},
+		{"!debug", []int{0, 2}},
+		{"!DEBUG !info", []int{0}},
+		{"error !", []int{0, 1}},
+		{"!", nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.term, func(t *testing.T) {
+			var indexes []int
+			for _, r := range ExclusionFilter(tc.term, targets) {
+				indexes = append(indexes, r.Index)
+			}
+			sort.Ints(indexes)
+			if fmt.Sprint(indexes) != fmt.Sprint(tc.expected) {
+				t.Fatalf("Error: expected %v to match, got %v", tc.expected, indexes)
+			}
+		})
+	}
+
+	ranks := ExclusionFilter("disk !debug", targets)
+	if len(ranks) != 1 || fmt.Sprint(ranks[0].MatchedIndexes) != "[7 8 9 10]" {
+		t.Fatalf("Error: expected only the included term to be highlighted, got %v", ranks)
+	}
+}
+
+func TestUpdateSync(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, plainDelegate{}, 10, 10)
+
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	if n := len(list.AvailableItems()); n != 2 {
+		t.Fatalf("Error: expected 2 matches while filtering, got %d", n)
+	}
+
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("z")})
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyEnter})
+	if list.FilterState() != FilterApplied || list.SelectedItem() != namedItem("baz") {
+		t.Fatalf("Error: expected %q to be selected, got %v (%s)", "baz", list.SelectedItem(), list.FilterState())
+	}
+}
+
+type msgDelegate struct {
+	plainDelegate
+	msgs *[]tea.Msg
+}
+
+func (d msgDelegate) Update(msg tea.Msg, m *Model) tea.Cmd {
+	if s, ok := msg.(string); ok {
+		*d.msgs = append(*d.msgs, s)
+	}
+	return nil
+}
+
+func TestUpdateSyncRunsCommands(t *testing.T) {
+	var msgs []tea.Msg
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, msgDelegate{msgs: &msgs}, 20, 10)
+	filtered := 0
+	list.Filter = func(term string, targets []string) []Rank {
+		filtered++
+		return DefaultFilter(term, targets)
+	}
+
+	// Messages from batched commands are handled, as are the messages of
+	// the commands handling them returns.
+	list.OnActivate = func(int, Item) tea.Cmd {
+		return tea.Batch(
+			func() tea.Msg { return "first" },
+			tea.Batch(nil, func() tea.Msg { return "second" }),
+		)
+	}
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyEnter})
+	if !slices.Contains(msgs, tea.Msg("first")) || !slices.Contains(msgs, tea.Msg("second")) {
+		t.Fatalf("Error: expected both messages to be handled, got %v", msgs)
+	}
+
+	// The filter is only run again when the filter or the items change.
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyEnter})
+	if filtered != 1 || len(list.AvailableItems()) != 2 {
+		t.Fatalf("Error: expected one filter run with 2 matches, got %d and %d", filtered, len(list.AvailableItems()))
+	}
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyDown})
+	if filtered != 1 || list.Index() != 1 {
+		t.Fatalf("Error: expected moving not to filter again, got %d runs", filtered)
+	}
+	list.SetItem(0, namedItem("bag"))
+	list = list.UpdateSync(nil)
+	if filtered != 1 {
+		t.Fatalf("Error: expected no filter run without a command, got %d", filtered)
+	}
+}
+
+func TestRenderPlain(t *testing.T) {
+	d := NewDefaultDelegate()
+	list := New([]Item{titledItem("foo"), titledItem("bar")}, d, 20, 10)
+	list.Title = "Fruit"
+
+	if s := stripANSI("\x1b[1;38;5;62mbold\x1b[0m text"); s != "bold text" {
+		t.Fatalf("Error: expected escape sequences to be stripped, got %q", s)
+	}
+
+	view := list.RenderPlain()
+	if strings.Contains(view, "\x1b") {
+		t.Fatalf("Error: expected no escape sequences, got %q", view)
+	}
+	if !strings.Contains(view, "Fruit") || !strings.Contains(view, "│ foo") {
+		t.Fatalf("Error: expected the rendered content to be kept:\n%s", view)
+	}
+	if lipgloss.Width(view) != lipgloss.Width(list.View()) || lipgloss.Height(view) != lipgloss.Height(list.View()) {
+		t.Fatalf("Error: expected the plain view to have the same size as the styled view")
+	}
+}
+
+func TestIncrementalFilter(t *testing.T) {
+	items := make([]Item, 100)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, plainDelegate{}, 60, 10)
+	list.FilterBatchSize = 30
+
+	msg := list.ApplyFilter("item")()
+	list, _ = list.Update(msg)
+	if n := len(list.AvailableItems()); n != 30 {
+		t.Fatalf("Error: expected the first batch to be shown, got %d matches", n)
+	}
+	if status := list.statusView(); !strings.Contains(status, "filtering… 30%") {
+		t.Fatalf("Error: expected the progress in the status bar, got %q", status)
+	}
+
+	// A new filter discards batches still in flight.
+	stale := filterBatch(list, 30)()
+	cmd := list.ApplyFilter("item 1")
+	before := len(list.AvailableItems())
+	list, _ = list.Update(stale)
+	if n := len(list.AvailableItems()); n != before {
+		t.Fatalf("Error: expected the stale batch to be discarded, got %d matches", n)
+	}
+
+	list = list.UpdateSync(cmd())
+	if n := len(list.AvailableItems()); n != 19 {
+		t.Fatalf("Error: expected 19 matches, got %d", n)
+	}
+	if status := list.statusView(); strings.Contains(status, "filtering…") {
+		t.Fatalf("Error: expected no progress once done, got %q", status)
+	}
+}
+
+func TestStaleFilterMatches(t *testing.T) {
+	list := New([]Item{
+		groupItem("fruits"), namedItem("apple"), namedItem("banana"), namedItem("cherry"),
+	}, plainDelegate{}, 20, 20)
+	list.CollapseAll()
+
+	// Matches for an earlier term don't replace those of the current one.
+	stale := list.ApplyFilter("an")()
+	list, _ = list.Update(list.ApplyFilter("ch")())
+	list, _ = list.Update(stale)
+	if n := len(list.AvailableItems()); n != 1 || list.AvailableItems()[0] != namedItem("cherry") {
+		t.Fatalf("Error: expected only cherry to match, got %v", list.AvailableItems())
+	}
+
+	// Nor do they expand groups once the filter has been reset.
+	stale = list.ApplyFilter("an")()
+	list.ResetFilter()
+	list.CollapseAll()
+	list, _ = list.Update(stale)
+	if list.FilterState() != Unfiltered || !list.IsCollapsed(groupItem("fruits")) {
+		t.Fatalf("Error: expected the reset filter to stay reset with the group collapsed, got %s", list.FilterState())
+	}
+}
+
+func TestFilterTargetsInvalidated(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("qux")}, plainDelegate{}, 10, 10)
+	list, _ = list.Update(list.ApplyFilter("qux")())
+	if n := len(list.AvailableItems()); n != 1 {
+		t.Fatalf("Error: expected 1 match, got %d", n)
+	}
+
+	list, _ = list.Update(list.SetItem(1, namedItem("qux"))())
+	if n := len(list.AvailableItems()); n != 2 {
+		t.Fatalf("Error: expected the changed item to match, got %d matches", n)
+	}
+}
+
+func BenchmarkFilterItems(b *testing.B) {
+	items := make([]Item, 50000)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item number %d", i))
+	}
+	list := New(items, plainDelegate{}, 80, 24)
+	list.ApplyFilter("num 42")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filterItems(list)()
+	}
+}
+
+type countedItem struct {
+	value string
+	calls *int
+}
+
+func (i countedItem) FilterValue() string {
+	*i.calls++
+	return i.value
+}
+
+func TestSetItemUpdatesFilterTargets(t *testing.T) {
+	var calls int
+	items := []Item{
+		countedItem{"foo", &calls},
+		countedItem{"bar", &calls},
+		countedItem{"qux", &calls},
+	}
+	list := New(items, plainDelegate{}, 10, 10)
+	list, _ = list.Update(list.ApplyFilter("qux")())
+
+	calls = 0
+	list, _ = list.Update(list.SetItem(1, countedItem{"qux", &calls})())
+	if calls != 1 {
+		t.Fatalf("Error: expected only the changed item's filter value to be computed, got %d calls", calls)
+	}
+	if n := len(list.AvailableItems()); n != 2 {
+		t.Fatalf("Error: expected the changed item to match, got %d matches", n)
+	}
+	if list.filterTargets[0] != "foo" || list.filterTargets[1] != "qux" {
+		t.Fatalf("Error: unexpected cached filter values %q", list.filterTargets)
+	}
+}
+
+func BenchmarkSetItemWhileFiltered(b *testing.B) {
+	items := make([]Item, 50000)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item number %d", i))
+	}
+	list := New(items, plainDelegate{}, 80, 24)
+	list.ApplyFilter("num 42")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filterItems(list)()
+		list.SetItem(i%len(items), namedItem(fmt.Sprintf("item number %d", i)))
+	}
+}
+
+func TestViewportBoundsCached(t *testing.T) {
+	items := make([]Item, 10)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, plainDelegate{}, 20, 3)
+	list.SetShowTitle(false)
+	list.SetShowFilter(false)
+	list.SetShowStatusBar(false)
+	list.SetShowHelp(false)
+
+	list, _ = list.Update(nil)
+	if list.viewportDirty {
+		t.Fatalf("Error: expected Update to compute the viewport bounds")
+	}
+
+	// Mutating methods mark the bounds as dirty so the view is correct
+	// without going through Update.
+	list.Select(5)
+	if !list.viewportDirty {
+		t.Fatalf("Error: expected Select to mark the viewport bounds as dirty")
+	}
+	if view := list.View(); !strings.Contains(view, "item 5") {
+		t.Fatalf("Error: expected the selected item to be visible:\n%s", view)
+	}
+
+	list, _ = list.Update(nil)
+	list.SetHeight(6)
+	if view := list.View(); !strings.Contains(view, "item 8") {
+		t.Fatalf("Error: expected resizing to grow the viewport:\n%s", view)
+	}
+}
+
+func TestViewportBoundsFollowExportedFields(t *testing.T) {
+	items := make([]Item, 50)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, plainDelegate{}, 40, 22)
+	list, _ = list.Update(nil)
+
+	// Showing the full help doesn't go through a method, so the bounds have
+	// to notice the chrome growing on their own.
+	list.Help.ShowAll = true
+	if h := lipgloss.Height(list.View()); h != 22 {
+		t.Fatalf("Error: expected the view to stay 22 lines with the full help, got %d", h)
+	}
+	list.Help.ShowAll = false
+	styles := list.Styles
+	styles.TitleBar = styles.TitleBar.Copy().Padding(3, 0, 3, 2)
+	list.SetStyles(styles)
+	if h := lipgloss.Height(list.View()); h != 22 {
+		t.Fatalf("Error: expected the view to stay 22 lines with a taller title bar, got %d", h)
+	}
+}
+
+func BenchmarkView(b *testing.B) {
+	items := make([]Item, 1000)
+	for i := range items {
+		items[i] = titledItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, NewDefaultDelegate(), 80, 40)
+	list, _ = list.Update(nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = list.View()
+	}
+}
+
+type recordingDelegate struct {
+	plainDelegate
+	rendered *[]int
+}
+
+func (d recordingDelegate) Render(w io.Writer, m Model, index int, listItem Item) {
+	*d.rendered = append(*d.rendered, index)
+	d.plainDelegate.Render(w, m, index, listItem)
+}
+
+func TestRenderOnlyVisibleItems(t *testing.T) {
+	items := make([]Item, 20)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	var rendered []int
+	list := New(items, recordingDelegate{rendered: &rendered}, 20, 4)
+	list.SetShowTitle(false)
+	list.SetShowFilter(false)
+	list.SetShowStatusBar(false)
+	list.SetShowHelp(false)
+	list.Select(10)
+
+	_ = list.View()
+
+	first, last := list.RenderedRange()
+	if first != 7 || last != 10 {
+		t.Fatalf("Error: expected items 7 to 10 to be in view, got %d to %d", first, last)
+	}
+	if fmt.Sprint(rendered) != "[7 8 9 10]" {
+		t.Fatalf("Error: expected only the items in view to be rendered, got %v", rendered)
+	}
+
+	list.SetItems(nil)
+	if first, last := list.RenderedRange(); last >= first {
+		t.Fatalf("Error: expected an empty range, got %d to %d", first, last)
+	}
+}
+
+type virtualSource struct {
+	n     int
+	calls *int
+}
+
+func (s virtualSource) Len() int { return s.n }
+
+func (s virtualSource) Item(i int) Item {
+	*s.calls++
+	return namedItem(fmt.Sprintf("item %d", i))
+}
+
+func TestItemSource(t *testing.T) {
+	var calls int
+	list := New(nil, plainDelegate{}, 20, 5)
+	list.SetShowTitle(false)
+	list.SetShowFilter(false)
+	list.SetShowStatusBar(false)
+	list.SetShowHelp(false)
+	list.SetItemSource(virtualSource{n: 1000000, calls: &calls})
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyEnd})
+	view := list.View()
+	if !strings.Contains(view, "item 999999") {
+		t.Fatalf("Error: expected the last item to be shown:\n%s", view)
+	}
+	if calls > 10 {
+		t.Fatalf("Error: expected only the visible items to be requested, got %d calls", calls)
+	}
+	if list.SelectedItem() != namedItem("item 999999") {
+		t.Fatalf("Error: expected the last item to be selected, got %v", list.SelectedItem())
+	}
+	if list.FilteringEnabled() {
+		t.Fatalf("Error: expected filtering to be disabled with an item source")
+	}
+
+	list.SetItems([]Item{namedItem("foo")})
+	if list.ItemSource() != nil || list.SelectedItem() != namedItem("foo") {
+		t.Fatalf("Error: expected SetItems to replace the source")
+	}
+	if !list.FilteringEnabled() || !list.KeyMap.Filter.Enabled() {
+		t.Fatalf("Error: expected filtering to be enabled again without a source")
+	}
+
+	// Filtering the user disabled stays disabled.
+	list.SetFilteringEnabled(false)
+	list.SetItemSource(virtualSource{n: 10, calls: &calls})
+	list.SetItems([]Item{namedItem("foo")})
+	if list.FilteringEnabled() {
+		t.Fatalf("Error: expected filtering to stay disabled")
+	}
+}
+
+func TestOnEmptyChange(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 10, 10)
+
+	var calls []bool
+	list.OnEmptyChange = func(empty bool) {
+		calls = append(calls, empty)
+	}
+
+	list.SetItems(nil)
+	list.SetItems(nil)
+	list.InsertItem(0, namedItem("foo"))
+	list.InsertItem(1, namedItem("bar"))
+
+	// Typing a filter which matches nothing empties the list, and typing more
+	// while it's still empty doesn't call the hook.
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("z")})
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("z")})
+	list.ResetFilter()
+
+	list.RemoveItem(0)
+	list.RemoveItem(0)
+
+	expected := []bool{true, false, true, false, true}
+	if fmt.Sprint(calls) != fmt.Sprint(expected) {
+		t.Fatalf("Error: expected OnEmptyChange calls %v, got %v", expected, calls)
+	}
+}
+
+type identifiedItem struct {
+	id, name string
+}
+
+func (i identifiedItem) FilterValue() string { return i.name }
+func (i identifiedItem) ID() string          { return i.id }
+
+func TestSetItemsKeepsIdentifiedSelection(t *testing.T) {
+	list := New([]Item{
+		identifiedItem{"a", "apple"},
+		identifiedItem{"b", "banana"},
+		identifiedItem{"c", "cherry"},
+	}, plainDelegate{}, 10, 10)
+	list.Select(1)
+
+	// The selected item moved and was renamed, but kept its identity.
+	list.SetItems([]Item{
+		identifiedItem{"c", "cherry"},
+		identifiedItem{"a", "apple"},
+		identifiedItem{"d", "date"},
+		identifiedItem{"b", "blueberry"},
+	})
+	if list.Index() != 3 {
+		t.Fatalf("Error: expected the selection to follow the item to 3, got %d", list.Index())
+	}
+
+	// While filtering, the selection follows the item once the filter has
+	// been recomputed.
+	list, _ = list.Update(list.ApplyFilter("e")())
+	list.Select(list.Index())
+	want := list.SelectedItem()
+	list, _ = list.Update(list.SetItems([]Item{
+		identifiedItem{"e", "elderberry"},
+		identifiedItem{"b", "blueberry"},
+		identifiedItem{"a", "apple"},
+		identifiedItem{"c", "cherry"},
+	})())
+	if got := list.SelectedItem(); got.(identifiedItem).id != want.(identifiedItem).id {
+		t.Fatalf("Error: expected %v to stay selected while filtering, got %v", want, got)
+	}
+
+	// Without identities the selection keeps its position.
+	plain := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 10, 10)
+	plain.Select(1)
+	plain.SetItems([]Item{namedItem("bar"), namedItem("foo")})
+	if plain.SelectedItem() != namedItem("foo") {
+		t.Fatalf("Error: expected the selection to keep its position, got %v", plain.SelectedItem())
+	}
+}
+
+func TestDeduplicateItemsByID(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("FOO"), namedItem("baz")}, plainDelegate{}, 10, 10)
+	list.IDFunc = func(i Item) string {
+		return strings.ToLower(i.FilterValue())
+	}
+	list.DeduplicateItems(nil)
+
+	expected := []Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}
+	if fmt.Sprint(list.Items()) != fmt.Sprint(expected) {
+		t.Fatalf("Error: expected items %v, got %v", expected, list.Items())
+	}
+}
+
+func TestDeduplicateItems(t *testing.T) {
+	key := func(i Item) string {
+		return strings.Fields(i.FilterValue())[0]
+	}
+	list := New([]Item{
+		namedItem("a 1"), namedItem("b 1"), namedItem("a 2"), namedItem("c 1"), namedItem("b 2"), namedItem("d 1"),
+	}, plainDelegate{}, 10, 10)
+
+	// The cursor is on a duplicate, so it moves to the item it duplicated.
+	list.Select(4)
+	list.DeduplicateItems(key)
+
+	expected := []Item{namedItem("a 1"), namedItem("b 1"), namedItem("c 1"), namedItem("d 1")}
+	if fmt.Sprint(list.Items()) != fmt.Sprint(expected) {
+		t.Fatalf("Error: expected items %v, got %v", expected, list.Items())
+	}
+	if list.SelectedItem() != namedItem("b 1") {
+		t.Fatalf("Error: expected the selection to move to %q, got %v", "b 1", list.SelectedItem())
+	}
+
+	// Without duplicates there's nothing to do.
+	if cmd := list.DeduplicateItems(key); cmd != nil {
+		t.Fatal("Error: expected no command without duplicates")
+	}
+
+	// While filtering, the items are filtered again.
+	list.SetItems(append(list.Items(), namedItem("c 2")))
+	list, _ = list.Update(list.ApplyFilter("c")())
+	list.Select(1)
+	cmd := list.DeduplicateItems(key)
+	if cmd == nil {
+		t.Fatal("Error: expected a command to filter the items again")
+	}
+	list, _ = list.Update(cmd())
+	if len(list.AvailableItems()) != 1 || list.SelectedItem() != namedItem("c 1") {
+		t.Fatalf("Error: expected only %q to be shown and selected, got %v", "c 1", list.AvailableItems())
+	}
+}
+
+func TestPersistentHighlight(t *testing.T) {
+	list := New([]Item{namedItem("apple"), namedItem("banana")}, plainDelegate{}, 20, 20)
+
+	if matches := list.HighlightsForItem(0); matches != nil {
+		t.Fatalf("Error: expected no highlights by default, got %v", matches)
+	}
+
+	list.PersistentHighlight = "le"
+	if matches := list.HighlightsForItem(0); fmt.Sprint(matches) != "[3 4]" {
+		t.Fatalf("Error: expected highlights [3 4] without a filter, got %v", matches)
+	}
+	if matches := list.HighlightsForItem(1); matches != nil {
+		t.Fatalf("Error: expected no highlights for an item which doesn't match, got %v", matches)
+	}
+
+	// The highlight takes precedence over the filter's matches.
+	list, _ = list.Update(list.ApplyFilter("ap")())
+	if matches := list.HighlightsForItem(0); fmt.Sprint(matches) != "[3 4]" {
+		t.Fatalf("Error: expected highlights [3 4] while filtered, got %v", matches)
+	}
+	list.PersistentHighlight = ""
+	if matches := list.HighlightsForItem(0); fmt.Sprint(matches) != fmt.Sprint(list.MatchesForItem(0)) {
+		t.Fatalf("Error: expected the filter's matches, got %v", matches)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	list := New([]Item{
+		namedItem("apple"), namedItem("banana"), namedItem("cherry"), namedItem("blueberry"), namedItem("date"),
+	}, plainDelegate{}, 20, 20)
+	list.Select(2)
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyCtrlF})
+	for _, r := range "b" {
+		list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+
+	// Typing selects the first match from where the search started, without
+	// hiding anything.
+	if list.Index() != 3 {
+		t.Fatalf("Error: expected the search to select 3, got %d", list.Index())
+	}
+	if n := len(list.AvailableItems()); n != 5 {
+		t.Fatalf("Error: expected all 5 items to stay visible, got %d", n)
+	}
+	if list.HighlightsForItem(1) == nil {
+		t.Fatal("Error: expected matches to be highlighted")
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if list.SearchTerm() != "b" {
+		t.Fatalf("Error: expected the search term to be kept, got %q", list.SearchTerm())
+	}
+
+	n := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")}
+	prev := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("N")}
+	var got []int
+	for _, msg := range []tea.KeyMsg{n, n, prev, prev} {
+		list, _ = list.Update(msg)
+		got = append(got, list.Index())
+	}
+	if expected := []int{1, 3, 1, 3}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("Error: expected n/N to cycle through %v, got %v", expected, got)
+	}
+
+	// Escape clears the search before clearing filters or quitting.
+	list, cmd := list.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if list.SearchTerm() != "" || list.HighlightsForItem(1) != nil {
+		t.Fatal("Error: expected escape to clear the search")
+	}
+	for _, msg := range collectMsgs(cmd) {
+		if _, ok := msg.(tea.QuitMsg); ok {
+			t.Fatal("Error: expected escape not to quit while searching")
+		}
+	}
+
+	// Cancelling a search restores the selection.
+	list.Select(0)
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyCtrlF})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if list.Index() != 4 {
+		t.Fatalf("Error: expected the search to select 4, got %d", list.Index())
+	}
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if list.Index() != 0 || list.SearchTerm() != "" {
+		t.Fatalf("Error: expected cancelling to restore the selection to 0, got %d", list.Index())
+	}
+}
+
+func TestCycleMatches(t *testing.T) {
+	list := New([]Item{
+		namedItem("apple"), namedItem("banana"), namedItem("cherry"), namedItem("blueberry"), namedItem("date"),
+	}, plainDelegate{}, 120, 40)
+	n := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")}
+	prev := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("N")}
+
+	// Without anything to match, the keys do nothing and aren't in the help.
+	list, _ = list.Update(n)
+	if list.Index() != 0 {
+		t.Fatalf("Error: expected the selection to stay at 0, got %d", list.Index())
+	}
+	list.Help.ShowAll = true
+	if strings.Contains(list.View(), "next match") {
+		t.Fatalf("Error: expected no match keys in the help without matches")
+	}
+
+	// The highlighted term's matches are cycled through while the full list
+	// is shown.
+	list.PersistentHighlight = "rr"
+	if !strings.Contains(list.View(), "next match") {
+		t.Fatalf("Error: expected the match keys in the help with a highlight")
+	}
+	var got []int
+	for _, msg := range []tea.KeyMsg{n, n, n, prev} {
+		list, _ = list.Update(msg)
+		got = append(got, list.Index())
+	}
+	if expected := []int{2, 3, 2, 3}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("Error: expected the highlight's matches %v, got %v", expected, got)
+	}
+
+	// With a filter applied, the keys cycle through its matches.
+	list.PersistentHighlight = ""
+	list, _ = list.Update(list.ApplyFilter("e")())
+	got = nil
+	for _, msg := range []tea.KeyMsg{prev, n, n} {
+		list, _ = list.Update(msg)
+		got = append(got, list.Index())
+	}
+	if expected := []int{len(list.AvailableItems()) - 1, 0, 1}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("Error: expected the filter's matches %v, got %v", expected, got)
+	}
+}
+
+func TestEscapeBehavior(t *testing.T) {
+	esc := tea.KeyMsg{Type: tea.KeyEsc}
+	quits := func(cmd tea.Cmd) bool {
+		for _, msg := range collectMsgs(cmd) {
+			if _, ok := msg.(tea.QuitMsg); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 10, 10)
+	if _, cmd := list.Update(esc); !quits(cmd) {
+		t.Fatal("Error: expected escape to quit by default")
+	}
+
+	list.SetEscapeBehavior(EscapeClearOnly)
+	list, _ = list.Update(list.ApplyFilter("fo")())
+	list, cmd := list.Update(esc)
+	if quits(cmd) || list.FilterState() != Unfiltered {
+		t.Fatal("Error: expected escape to clear the filter without quitting")
+	}
+	if _, cmd := list.Update(esc); quits(cmd) {
+		t.Fatal("Error: expected escape not to quit with nothing to clear")
+	}
+	if _, cmd := list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}); !quits(cmd) {
+		t.Fatal("Error: expected q to still quit")
+	}
+
+	type closeMsg struct{}
+	list.SetEscapeBehavior(EscapeCustom)
+	list.OnEscape = func() tea.Cmd {
+		return func() tea.Msg { return closeMsg{} }
+	}
+	_, cmd = list.Update(esc)
+	msgs := collectMsgs(cmd)
+	if quits(cmd) || !slices.Contains(msgs, tea.Msg(closeMsg{})) {
+		t.Fatalf("Error: expected escape to call OnEscape without quitting, got %v", msgs)
+	}
+}
+
+func TestConfirmQuit(t *testing.T) {
+	quits := func(cmd tea.Cmd) bool {
+		for _, msg := range collectMsgs(cmd) {
+			if _, ok := msg.(tea.QuitMsg); ok {
+				return true
+			}
+		}
+		return false
+	}
+	q := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}
+
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 20, 10)
+	list.ConfirmQuit = true
+
+	list, cmd := list.Update(q)
+	if quits(cmd) || !list.ConfirmingQuit() {
+		t.Fatal("Error: expected q to ask for confirmation")
+	}
+	if view := list.RenderPlain(); !strings.Contains(view, "Quit? (y/n)") {
+		t.Fatalf("Error: expected the prompt to be shown, got %q", view)
+	}
+
+	// Other keys are ignored while asking.
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if list.Index() != 0 || !list.ConfirmingQuit() {
+		t.Fatal("Error: expected other keys to be ignored while asking")
+	}
+
+	list, cmd = list.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if quits(cmd) || list.ConfirmingQuit() {
+		t.Fatal("Error: expected escape to cancel quitting")
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if !list.ConfirmingQuit() {
+		t.Fatal("Error: expected ctrl+c to ask for confirmation")
+	}
+	if _, cmd = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")}); !quits(cmd) {
+		t.Fatal("Error: expected y to quit")
+	}
+}
+
+func TestCachedItem(t *testing.T) {
+	var calls int
+	items := WrapCached([]Item{
+		countedItem{"apple", &calls},
+		countedItem{"banana", &calls},
+		titledItem("cherry"),
+	})
+
+	list := New(items, plainDelegate{}, 10, 10)
+	for _, term := range []string{"a", "an", "b"} {
+		list, _ = list.Update(list.ApplyFilter(term)())
+		list.SetItems(list.Items())
+	}
+	if calls != 2 {
+		t.Fatalf("Error: expected each filter value to be computed once, got %d calls", calls)
+	}
+
+	if title := items[2].(DefaultItem).Title(); title != "cherry" {
+		t.Fatalf("Error: expected the title to be forwarded, got %q", title)
+	}
+	if title := items[0].(DefaultItem).Title(); title != "" {
+		t.Fatalf("Error: expected an empty title for an item without one, got %q", title)
+	}
+	if item := items[2].(*CachedItem).Unwrap(); item != titledItem("cherry") {
+		t.Fatalf("Error: expected Unwrap to return the wrapped item, got %v", item)
+	}
+}
+
+func TestCachedItemInterfaces(t *testing.T) {
+	list := New(WrapCached([]Item{
+		groupItem("fruits"),
+		identifiedItem{"1", "apple"},
+		identifiedItem{"2", "banana"},
+		namedItem("plain"),
+	}), NewDefaultDelegate(), 30, 20)
+
+	// The list looks through the wrapper for optional interfaces.
+	if !list.isGroup(list.Items()[0]) {
+		t.Fatalf("Error: expected a wrapped group item to head a group")
+	}
+	if id, ok := list.itemID(list.Items()[1]); !ok || id != "1" {
+		t.Fatalf("Error: expected a wrapped item's identity, got %q", id)
+	}
+	list.Select(2)
+	list.SetItems(WrapCached([]Item{groupItem("fruits"), identifiedItem{"2", "banana"}}))
+	if list.Index() != 1 {
+		t.Fatalf("Error: expected the selection to follow the wrapped item, got %d", list.Index())
+	}
+
+	// Items which aren't DefaultItems aren't rendered with a blank title.
+	var b strings.Builder
+	list.delegate.Render(&b, list, 0, NewCachedItem(namedItem("plain")))
+	if b.Len() != 0 {
+		t.Fatalf("Error: expected nothing rendered for a wrapped plain item, got %q", b.String())
+	}
+}
+
+func TestHelpWidth(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 30, 20)
+
+	// The help's padding is taken into account, so it's cut short rather
+	// than running past the width of the list and wrapping.
+	for _, line := range strings.Split(list.helpView(), "\n") {
+		if w := lipgloss.Width(line); w > 30 {
+			t.Fatalf("Error: expected help lines to fit in 30 columns, got %d: %q", w, line)
+		}
+	}
+
+	list.SetHelpWidth(12)
+	list.SetSize(80, 20)
+	help := list.helpView()
+	for _, line := range strings.Split(help, "\n") {
+		if w := lipgloss.Width(line); w > 12 {
+			t.Fatalf("Error: expected help lines to fit in 12 columns, got %d: %q", w, line)
+		}
+	}
+	if h := lipgloss.Height(help); h != lipgloss.Height(list.Styles.HelpStyle.Render("x")) {
+		t.Fatalf("Error: expected the help to keep its height, got %d", h)
+	}
+}
+
+func TestAllBindings(t *testing.T) {
+	km := DefaultKeyMap()
+	km.Quit.SetEnabled(false)
+	bindings := km.AllBindings()
+
+	// Every binding in the key map is returned, in order.
+	v := reflect.ValueOf(km)
+	if len(bindings) != v.NumField() {
+		t.Fatalf("Error: expected %d bindings, got %d", v.NumField(), len(bindings))
+	}
+	for i, b := range bindings {
+		field := v.Field(i).Interface().(key.Binding)
+		if fmt.Sprint(b.Keys()) != fmt.Sprint(field.Keys()) || b.Help() != field.Help() {
+			t.Fatalf("Error: expected binding %d to be %s, got %v", i, v.Type().Field(i).Name, b.Keys())
+		}
+	}
+
+	quit, _ := v.Type().FieldByName("Quit")
+	if bindings[quit.Index[0]].Enabled() {
+		t.Fatal("Error: expected disabled bindings to be returned as they are")
+	}
+}
+
+func TestSetKeyMap(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, plainDelegate{}, 10, 10)
+	list, _ = list.Update(list.ApplyFilter("ba")())
+
+	km := DefaultKeyMap()
+	km.CursorDown = key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "down"))
+	km.CursorDown.SetEnabled(false)
+	list.SetKeyMap(km)
+
+	if !list.KeyMap.CursorDown.Enabled() || !list.KeyMap.ClearFilter.Enabled() {
+		t.Fatal("Error: expected navigation and clearing the filter to be enabled")
+	}
+	if list.KeyMap.CancelWhileFiltering.Enabled() || list.KeyMap.AcceptWhileFiltering.Enabled() {
+		t.Fatal("Error: expected the filter editing bindings to be disabled")
+	}
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	if list.Index() != 1 {
+		t.Fatalf("Error: expected the new binding to move the cursor to 1, got %d", list.Index())
+	}
+}
+
+type loadingItem struct {
+	title   string
+	loading bool
+}
+
+func (i loadingItem) FilterValue() string { return i.title }
+func (i loadingItem) Title() string       { return i.title }
+func (i loadingItem) IsLoading() bool     { return i.loading }
+
+func TestLoadingItemSpinner(t *testing.T) {
+	d := NewDefaultDelegate()
+	list := New([]Item{
+		loadingItem{"foo", true},
+		loadingItem{"bar", false},
+	}, d, 20, 10)
+	list.SetSpinner(spinner.Spinner{Frames: []string{"@", "#"}, FPS: time.Second})
+
+	var b strings.Builder
+	d.Render(&b, list, 0, list.Items()[0])
+	if !strings.Contains(stripANSI(b.String()), "foo @") {
+		t.Fatalf("Error: expected a spinner next to the loading item, got %q", b.String())
+	}
+	b.Reset()
+	d.Render(&b, list, 1, list.Items()[1])
+	if strings.Contains(stripANSI(b.String()), "@") {
+		t.Fatalf("Error: expected no spinner next to an item which isn't loading, got %q", b.String())
+	}
+
+	// The spinner keeps ticking while items in view are loading, even though
+	// it isn't shown in the title bar.
+	list, cmd := list.Update(list.StartItemSpinner()())
+	if cmd == nil {
+		t.Fatal("Error: expected the spinner to keep ticking")
+	}
+	list.SetItem(0, loadingItem{"foo", false})
+	if _, cmd := list.Update(list.StartItemSpinner()()); cmd != nil {
+		t.Fatal("Error: expected the spinner to stop once nothing is loading")
+	}
+}
+
+func TestDoubleClickActivates(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, plainDelegate{}, 20, 20)
+	list.SetMouseEnabled(true)
+	var activated []int
+	list.OnActivate = func(index int, _ Item) tea.Cmd {
+		activated = append(activated, index)
+		return nil
+	}
+
+	row := -1
+	for i, line := range strings.Split(list.RenderPlain(), "\n") {
+		if strings.Contains(line, "2. bar") {
+			row = i
+		}
+	}
+	click := tea.MouseMsg{Type: tea.MouseLeft, Y: row}
+
+	// The first click selects, the second activates.
+	list, _ = list.Update(click)
+	if list.Index() != 1 || len(activated) != 0 {
+		t.Fatalf("Error: expected a click to select 1 without activating, got %d and %v", list.Index(), activated)
+	}
+	list, _ = list.Update(click)
+	if fmt.Sprint(activated) != "[1]" {
+		t.Fatalf("Error: expected a double click to activate 1, got %v", activated)
+	}
+
+	// Clicks too far apart don't activate.
+	list.DoubleClickInterval = 0
+	list, _ = list.Update(click)
+	list, _ = list.Update(click)
+	if len(activated) != 1 {
+		t.Fatalf("Error: expected slow clicks not to activate, got %v", activated)
+	}
+
+	// Clicking the title does nothing, and enter still activates.
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseLeft, Y: 0})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if list.Index() != 1 || fmt.Sprint(activated) != "[1 1]" {
+		t.Fatalf("Error: expected enter to activate 1, got %d and %v", list.Index(), activated)
+	}
+}
+
+func TestFilterMatchCount(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, plainDelegate{}, 24, 10)
+	list.SetShowStatusBar(false)
+	list.SetShowFilterMatchCount(true)
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	list, _ = list.Update(filterItems(list)())
+
+	title := strings.Split(list.RenderPlain(), "\n")[0]
+	if !strings.Contains(title, "Filter: b") || !strings.Contains(title, "(2)") {
+		t.Fatalf("Error: expected the match count next to the filter input, got %q", title)
+	}
+	if w := lipgloss.Width(title); w > 24 {
+		t.Fatalf("Error: expected the title bar to fit in 24 columns, got %d: %q", w, title)
+	}
+
+	list.SetShowFilterMatchCount(false)
+	if title := strings.Split(list.RenderPlain(), "\n")[0]; strings.Contains(title, "(2)") {
+		t.Fatalf("Error: expected no match count once hidden, got %q", title)
+	}
+}
+
+func TestRevealAt(t *testing.T) {
+	items := make([]Item, 20)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, plainDelegate{}, 20, 5)
+	list.SetShowTitle(false)
+	list.SetShowFilter(false)
+	list.SetShowStatusBar(false)
+	list.SetShowHelp(false)
+
+	tests := []struct {
+		index, offset int
+		first, last   int
+	}{
+		{10, 2, 8, 12},
+		{10, 0, 10, 14},
+		{10, 9, 6, 10},
+		{1, 3, 0, 4},
+		{19, 0, 15, 19},
+	}
+	for _, tc := range tests {
+		list.Select(tc.index)
+		list.RevealAt(tc.offset)
+		if first, last := list.RenderedRange(); first != tc.first || last != tc.last {
+			t.Fatalf("Error: expected RevealAt(%d) with %d selected to show %d-%d, got %d-%d",
+				tc.offset, tc.index, tc.first, tc.last, first, last)
+		}
+	}
+
+	// Moving within the view keeps it where it is.
+	list.Select(10)
+	list.RevealAt(2)
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if first, _ := list.RenderedRange(); first != 8 {
+		t.Fatalf("Error: expected the view to stay at 8, got %d", first)
+	}
+}
+
+func TestFilterCompletion(t *testing.T) {
+	list := New([]Item{namedItem("apple"), namedItem("Banana"), namedItem("cherry")}, plainDelegate{}, 40, 10)
+	list.SetShowFilterCompletion(true)
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	for _, r := range "ba" {
+		list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	list, _ = list.Update(filterItems(list)())
+
+	title := strings.Split(list.RenderPlain(), "\n")[0]
+	if !strings.Contains(title, "ba nana") {
+		t.Fatalf("Error: expected the completion after the cursor, got %q", title)
+	}
+
+	// Tab fills in the completion instead of accepting the filter.
+	list, cmd := list.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if list.FilterValue() != "banana" || list.FilterState() != Filtering {
+		t.Fatalf("Error: expected the filter to be completed to %q, got %q", "banana", list.FilterValue())
+	}
+	list, _ = list.Update(cmd())
+	if completion := list.filterCompletion(); completion != "" {
+		t.Fatalf("Error: expected no completion once completed, got %q", completion)
+	}
+
+	// Without a completion, tab accepts the filter as before.
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if list.FilterState() != FilterApplied {
+		t.Fatalf("Error: expected tab to accept the filter, got %v", list.FilterState())
+	}
+}
+
+type liveItem struct {
+	key, value string
+	rev        int
+}
+
+func (i liveItem) FilterValue() string { return i.value }
+
+func TestApplyDiff(t *testing.T) {
+	key := func(i Item) string { return i.(liveItem).key }
+	items := make([]Item, 20)
+	for i := range items {
+		items[i] = liveItem{fmt.Sprint(i), fmt.Sprintf("item %d", i), 0}
+	}
+	list := New(items, plainDelegate{}, 20, 5)
+	list.SetShowTitle(false)
+	list.SetShowFilter(false)
+	list.SetShowStatusBar(false)
+	list.SetShowHelp(false)
+	list.Select(10)
+	list.RevealAt(2)
+
+	// Items are added before the selection and one is removed after it.
+	newItems := []Item{
+		liveItem{"new a", "new a", 0},
+		liveItem{"new b", "new b", 0},
+		liveItem{"new c", "new c", 0},
+	}
+	for _, item := range items {
+		if key(item) != "15" {
+			newItems = append(newItems, item)
+		}
+	}
+	if cmd := list.ApplyDiff(newItems, key); cmd != nil {
+		t.Fatal("Error: expected no command without a filter")
+	}
+	if key(list.SelectedItem()) != "10" || list.Index() != 13 {
+		t.Fatalf("Error: expected item 10 to stay selected at 13, got %v at %d", list.SelectedItem(), list.Index())
+	}
+	if first, _ := list.RenderedRange(); first != 11 {
+		t.Fatalf("Error: expected the selection to stay 2 items below the top at 11, got %d", first)
+	}
+
+	// With a filter, updates which don't change filter values don't filter
+	// again.
+	list, _ = list.Update(list.ApplyFilter("item 1")())
+	selected := list.SelectedItem()
+	updated := append([]Item(nil), newItems...)
+	updated[5] = liveItem{"2", "item 2", 1}
+	if cmd := list.ApplyDiff(updated, key); cmd != nil {
+		t.Fatal("Error: expected no filtering when filter values didn't change")
+	}
+	if list.Items()[5].(liveItem).rev != 1 {
+		t.Fatal("Error: expected the item to be updated")
+	}
+
+	// Otherwise the items are filtered again, and the selection follows.
+	updated = append(updated[:1], updated[2:]...)
+	cmd := list.ApplyDiff(updated, key)
+	if cmd == nil {
+		t.Fatal("Error: expected the items to be filtered again")
+	}
+	list, _ = list.Update(cmd())
+	if key(list.SelectedItem()) != key(selected) {
+		t.Fatalf("Error: expected %v to stay selected, got %v", selected, list.SelectedItem())
+	}
+}
+
+func TestBeginEndUpdate(t *testing.T) {
+	items := make([]Item, 20)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, plainDelegate{}, 20, 5)
+	list.SetShowTitle(false)
+	list.SetShowFilter(false)
+	list.SetShowStatusBar(false)
+	list.SetShowHelp(false)
+	list.Select(10)
+	list.RevealAt(2)
+
+	var scrolls int
+	list.OnScroll = func(int, int) { scrolls++ }
+
+	list.BeginUpdate()
+	list.BeginUpdate()
+	for i := 0; i < 5; i++ {
+		list.InsertItem(0, namedItem(fmt.Sprintf("new %d", i)))
+		list, _ = list.Update(nil)
+		_ = list.View()
+	}
+	list.EndUpdate()
+	if first, last := list.RenderedRange(); first != 8 || last != 12 || scrolls != 0 {
+		t.Fatalf("Error: expected the view to stay at 8-12 until the last EndUpdate, got %d-%d", first, last)
+	}
+	list.EndUpdate()
+
+	if list.SelectedItem() != namedItem("item 10") || list.Index() != 15 {
+		t.Fatalf("Error: expected item 10 to stay selected at 15, got %v at %d", list.SelectedItem(), list.Index())
+	}
+	if first, _ := list.RenderedRange(); first != 13 {
+		t.Fatalf("Error: expected the selection to stay 2 items below the top at 13, got %d", first)
+	}
+}
+
+func TestMasterIndex(t *testing.T) {
+	list := New([]Item{namedItem("apple"), namedItem("banana"), namedItem("cherry"), namedItem("avocado")}, plainDelegate{}, 10, 10)
+	if got := list.MasterIndex(2); got != 2 {
+		t.Fatalf("Error: expected 2 when unfiltered, got %d", got)
+	}
+
+	list, _ = list.Update(list.ApplyFilter("a")())
+	var got []int
+	for i := range list.AvailableItems() {
+		got = append(got, list.MasterIndex(i))
+	}
+	for i, index := range got {
+		if list.Items()[index] != list.AvailableItems()[i] {
+			t.Fatalf("Error: expected MasterIndex(%d) to point at %v, got %v", i, list.AvailableItems()[i], list.Items()[index])
+		}
+	}
+	if got := list.MasterIndex(len(list.AvailableItems())); got != -1 {
+		t.Fatalf("Error: expected -1 out of range, got %d", got)
+	}
+}
+
+func TestRemoveItemWhileFiltered(t *testing.T) {
+	list := New([]Item{namedItem("apple"), namedItem("banana"), namedItem("cherry"), namedItem("avocado")}, plainDelegate{}, 10, 10)
+	list, _ = list.Update(list.ApplyFilter("a")())
+
+	// Removing banana, at master index 1, keeps the matches of the other
+	// items and where they point.
+	list.RemoveItem(1)
+	var got []string
+	for i, it := range list.AvailableItems() {
+		got = append(got, it.FilterValue())
+		if list.Items()[list.MasterIndex(i)] != it {
+			t.Fatalf("Error: expected MasterIndex(%d) to point at %v, got %v", i, it, list.Items()[list.MasterIndex(i)])
+		}
+	}
+	if fmt.Sprint(got) != "[apple avocado]" {
+		t.Fatalf("Error: expected apple and avocado to remain, got %v", got)
+	}
+
+	// Removing an item which didn't match only shifts the indexes.
+	list.RemoveItem(1)
+	if list.MasterIndex(1) != 1 || list.AvailableItems()[1] != namedItem("avocado") {
+		t.Fatalf("Error: expected avocado to be at master index 1, got %d", list.MasterIndex(1))
+	}
+}
+
+type groupItem string
+
+func (g groupItem) FilterValue() string { return string(g) }
+func (g groupItem) GroupTitle() string  { return string(g) }
+
+func TestCollapsibleGroups(t *testing.T) {
+	list := New([]Item{
+		groupItem("fruits"), namedItem("apple"), namedItem("banana"),
+		groupItem("vegetables"), namedItem("carrot"),
+	}, plainDelegate{}, 20, 20)
+	var activated []Item
+	list.OnActivate = func(_ int, i Item) tea.Cmd {
+		activated = append(activated, i)
+		return nil
+	}
+
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+	list, _ = list.Update(enter)
+	if !list.IsCollapsed(groupItem("fruits")) || len(activated) != 0 {
+		t.Fatalf("Error: expected enter to collapse the group instead of activating it")
+	}
+	want := []Item{groupItem("fruits"), groupItem("vegetables"), namedItem("carrot")}
+	if got := list.AvailableItems(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Error: expected %v, got %v", want, got)
+	}
+
+	list.CursorDown()
+	if list.SelectedItem() != groupItem("vegetables") || list.MasterIndex(list.Index()) != 3 {
+		t.Fatalf("Error: expected the cursor to skip the collapsed items, got %v", list.SelectedItem())
+	}
+	list.CursorDown()
+	list, _ = list.Update(enter)
+	if len(activated) != 1 || activated[0] != namedItem("carrot") {
+		t.Fatalf("Error: expected enter to activate items which aren't groups, got %v", activated)
+	}
+
+	list, _ = list.Update(list.ApplyFilter("banana")())
+	if list.IsCollapsed(groupItem("fruits")) {
+		t.Fatalf("Error: expected filtering to expand the group containing a match")
+	}
+	list.ResetFilter()
+	if got := len(list.AvailableItems()); got != 5 {
+		t.Fatalf("Error: expected all 5 items once the group is expanded, got %d", got)
+	}
+}
+
+func TestExpandCollapseAll(t *testing.T) {
+	list := New([]Item{
+		namedItem("pinned"),
+		groupItem("fruits"), namedItem("apple"), namedItem("banana"),
+		groupItem("vegetables"), namedItem("carrot"),
+	}, plainDelegate{}, 20, 20)
+	list.Select(3)
+
+	_ = list.CollapseAll()
+	want := []Item{namedItem("pinned"), groupItem("fruits"), groupItem("vegetables")}
+	if got := list.AvailableItems(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Error: expected %v, got %v", want, got)
+	}
+	if list.SelectedItem() != groupItem("fruits") {
+		t.Fatalf("Error: expected the group of the hidden selection to be selected, got %v", list.SelectedItem())
+	}
+
+	list.CursorDown()
+	_ = list.ExpandAll()
+	if got := len(list.AvailableItems()); got != 6 {
+		t.Fatalf("Error: expected all 6 items, got %d", got)
+	}
+	if list.SelectedItem() != groupItem("vegetables") {
+		t.Fatalf("Error: expected the selection to stay on vegetables, got %v", list.SelectedItem())
+	}
+}
+
+func TestSectionHeaderFunc(t *testing.T) {
+	d := NewDefaultDelegate()
+	list := New([]Item{
+		groupItem("fruits"), titledItem("apple"), titledItem("banana"), titledItem("cherry"),
+		groupItem("vegetables"), titledItem("carrot"),
+	}, d, 30, 20)
+	list.SectionHeaderFunc = func(title string, total, matched int) string {
+		return fmt.Sprintf("%s (%d/%d)", title, matched, total)
+	}
+
+	render := func(index int) string {
+		var b strings.Builder
+		d.Render(&b, list, index, list.AvailableItems()[index])
+		return b.String()
+	}
+	if got := render(0); !strings.Contains(got, "fruits (3/3)") {
+		t.Fatalf("Error: expected %q, got %q", "fruits (3/3)", got)
+	}
+
+	list, _ = list.Update(list.ApplyFilter("r")())
+	i := slices.Index(list.AvailableItems(), Item(groupItem("fruits")))
+	if i < 0 {
+		t.Fatalf("Error: expected fruits to match the filter")
+	}
+	if total, matched := list.GroupCounts(i); total != 3 || matched != 1 {
+		t.Fatalf("Error: expected 1 of 3 fruits to match, got %d of %d", matched, total)
+	}
+	if got := render(i); !strings.Contains(got, "fruits (1/3)") {
+		t.Fatalf("Error: expected %q, got %q", "fruits (1/3)", got)
+	}
+}
+
+func TestToggleFilterApplied(t *testing.T) {
+	list := New([]Item{namedItem("apple"), namedItem("banana"), namedItem("cherry")}, plainDelegate{}, 10, 10)
+	list, _ = list.Update(list.ApplyFilter("an")())
+
+	ctrlT := tea.KeyMsg{Type: tea.KeyCtrlT}
+	list, _ = list.Update(ctrlT)
+	if list.FilterState() != Unfiltered || !list.FilterHidden() || len(list.AvailableItems()) != 3 {
+		t.Fatalf("Error: expected all items to be shown while the filter is hidden")
+	}
+	if list.FilterValue() != "an" || list.SelectedItem() != namedItem("banana") {
+		t.Fatalf("Error: expected the term and the selection to be kept, got %q and %v", list.FilterValue(), list.SelectedItem())
+	}
+
+	list, cmd := list.Update(ctrlT)
+	for _, msg := range collectMsgs(cmd) {
+		list, _ = list.Update(msg)
+	}
+	if list.FilterState() != FilterApplied || len(list.AvailableItems()) != 1 || list.SelectedItem() != namedItem("banana") {
+		t.Fatalf("Error: expected the filter to be applied again, got %v", list.AvailableItems())
+	}
+
+	list, _ = list.Update(ctrlT)
+	list.ResetFilter()
+	if list.FilterHidden() || list.FilterValue() != "" {
+		t.Fatalf("Error: expected clearing the hidden filter to forget its term, got %q", list.FilterValue())
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	matched := lipgloss.NewStyle().Bold(true)
+	unmatched := lipgloss.NewStyle()
+	want := lipgloss.StyleRunes("banana", []int{0, 2}, matched.Inline(true), unmatched.Inline(true))
+	if got := HighlightMatches("banana", []int{0, 2}, matched, unmatched); got != want {
+		t.Fatalf("Error: expected %q, got %q", want, got)
+	}
+	if got := HighlightMatches("banana", nil, matched, unmatched); lipgloss.Width(got) != 6 {
+		t.Fatalf("Error: expected the text to be kept without matches, got %q", got)
+	}
+}
+
+func TestClearFilterText(t *testing.T) {
+	list := New([]Item{namedItem("apple"), namedItem("banana"), namedItem("cherry")}, plainDelegate{}, 10, 10)
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	for _, r := range "an" {
+		list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	list.FilterInput.SetCursor(1)
+	if got := len(list.AvailableItems()); got != 1 {
+		t.Fatalf("Error: expected 1 match, got %d", got)
+	}
+
+	list = list.UpdateSync(tea.KeyMsg{Type: tea.KeyCtrlU})
+	if list.FilterState() != Filtering || list.FilterValue() != "" {
+		t.Fatalf("Error: expected the whole filter to be cleared while still filtering, got %s with %q", list.FilterState(), list.FilterValue())
+	}
+	if got := len(list.AvailableItems()); got != 3 {
+		t.Fatalf("Error: expected all 3 items, got %d", got)
+	}
+}
+
+func TestOnReorder(t *testing.T) {
+	list := New([]Item{namedItem("a"), namedItem("b"), namedItem("c")}, plainDelegate{}, 10, 10)
+	var moves [][2]int
+	list.OnReorder = func(from, to int) {
+		moves = append(moves, [2]int{from, to})
+	}
+
+	list.MoveItemUp(0)
+	list.MoveItemDown(2)
+	if len(moves) != 0 {
+		t.Fatalf("Error: expected no calls for moves at the boundaries, got %v", moves)
+	}
+
+	list.MoveItemDown(0)
+	list.MoveItemUp(2)
+	want := [][2]int{{0, 1}, {2, 1}}
+	if !reflect.DeepEqual(moves, want) {
+		t.Fatalf("Error: expected %v, got %v", want, moves)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	list := New([]Item{namedItem("a"), namedItem("b"), namedItem("c")}, plainDelegate{}, 10, 10)
+	list.SetReadOnly(true)
+
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("J")})
+	if list.Items()[0] != namedItem("a") {
+		t.Fatalf("Error: expected items not to be moved, got %v", list.Items())
+	}
+
+	list, _ = list.Update(list.ApplyFilter("b")())
+	list.ResetFilter()
+	if list.KeyMap.MoveUp.Enabled() || list.KeyMap.MoveDown.Enabled() {
+		t.Fatalf("Error: expected the move keybindings to stay disabled after filtering")
+	}
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if list.Index() != 1 {
+		t.Fatalf("Error: expected navigation to work, got index %d", list.Index())
+	}
+
+	list.SetReadOnly(false)
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("J")})
+	if list.Items()[2] != namedItem("b") {
+		t.Fatalf("Error: expected b to be moved down, got %v", list.Items())
+	}
+}
+
+func TestViewRange(t *testing.T) {
+	items := make([]Item, 10)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, plainDelegate{}, 20, 20)
+
+	want := "4. item 3\n5. item 4\n6. item 5"
+	if got := list.ViewRange(3, 3); got != want {
+		t.Fatalf("Error: expected %q, got %q", want, got)
+	}
+	if got := list.ViewRange(8, 5); got != "9. item 8\n10. item 9" {
+		t.Fatalf("Error: expected the range to be clamped, got %q", got)
+	}
+	if got := list.ViewRange(12, 3); got != "" {
+		t.Fatalf("Error: expected nothing past the end, got %q", got)
+	}
+}
+
+func TestFilterSpinner(t *testing.T) {
+	list := New([]Item{namedItem("apple"), namedItem("banana")}, plainDelegate{}, 30, 10)
+	list.SetShowFilterSpinner(true)
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+
+	frame := list.spinnerView()
+	if !strings.Contains(list.titleView(), frame) {
+		t.Fatalf("Error: expected the spinner while filtering, got %q", list.titleView())
+	}
+	var ticks bool
+	for _, msg := range collectMsgs(list.refilter()) {
+		if _, ok := msg.(spinner.TickMsg); ok {
+			ticks = true
+		}
+	}
+	if !ticks {
+		t.Fatalf("Error: expected the spinner to be started")
+	}
+
+	list, _ = list.Update(filterItems(list)())
+	if strings.Contains(list.titleView(), frame) {
+		t.Fatalf("Error: expected the spinner to be hidden once the matches arrive, got %q", list.titleView())
+	}
+}
+
+func TestIsSelected(t *testing.T) {
+	list := New([]Item{namedItem("a"), namedItem("b")}, plainDelegate{}, 10, 10)
+	list.Select(1)
+	if list.IsSelected(0) || !list.IsSelected(1) || list.IsSelected(2) {
+		t.Fatalf("Error: expected only the item at the cursor to be selected")
+	}
+
+	list.SetItems(nil)
+	if list.IsSelected(list.Index()) {
+		t.Fatalf("Error: expected nothing to be selected without items")
+	}
+}
+
+func TestCompactAdaptiveHelp(t *testing.T) {
+	list := New([]Item{namedItem("a"), namedItem("b")}, plainDelegate{}, 40, 20)
+	list.SetHelpStyle(HelpCompactAdaptive)
+
+	lines := strings.Split(stripANSI(list.helpView()), "\n")
+	help := lines[len(lines)-1]
+	if !strings.Contains(help, "more") || !strings.Contains(help, "? more") {
+		t.Fatalf("Error: expected the bindings which don't fit to be counted, got %q", help)
+	}
+	if lipgloss.Width(help) > 40 {
+		t.Fatalf("Error: expected the help to fit on one line, got %q", help)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(help), "↑/k up") {
+		t.Fatalf("Error: expected the first bindings to be kept, got %q", help)
+	}
+
+	list.SetWidth(200)
+	if help := stripANSI(list.helpView()); strings.Contains(help, "+") {
+		t.Fatalf("Error: expected every binding to fit, got %q", help)
+	}
+}
+
+func TestPinItem(t *testing.T) {
+	list := New([]Item{namedItem("apple"), namedItem("banana"), namedItem("cherry"), namedItem("avocado")}, plainDelegate{}, 20, 20)
+	list.Select(1)
+	list.PinItem(3)
+	list.PinItem(2)
+
+	want := []Item{namedItem("cherry"), namedItem("avocado"), namedItem("apple"), namedItem("banana")}
+	if got := list.AvailableItems(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Error: expected pinned items first in their order, got %v", got)
+	}
+	if list.SelectedItem() != namedItem("banana") {
+		t.Fatalf("Error: expected the selection to stay on banana, got %v", list.SelectedItem())
+	}
+
+	list, _ = list.Update(list.ApplyFilter("a")())
+	if got := list.AvailableItems(); got[0] != namedItem("avocado") || slices.Contains(got, Item(namedItem("cherry"))) {
+		t.Fatalf("Error: expected the matching pinned item first and cherry filtered out, got %v", got)
+	}
+	list.ResetFilter()
+
+	list.SetItems([]Item{namedItem("cherry"), namedItem("apple"), namedItem("banana")})
+	if !list.IsPinned(namedItem("cherry")) || list.IsPinned(namedItem("apple")) {
+		t.Fatalf("Error: expected items to stay pinned by key")
+	}
+	list.UnpinItem(0)
+	want = []Item{namedItem("cherry"), namedItem("apple"), namedItem("banana")}
+	if got := list.AvailableItems(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Error: expected the original order once unpinned, got %v", got)
+	}
+}
+
+func TestRecents(t *testing.T) {
+	list := New([]Item{namedItem("a"), namedItem("b"), namedItem("c"), namedItem("d")}, plainDelegate{}, 20, 20)
+	var activated []Item
+	list.OnActivate = func(_ int, i Item) tea.Cmd {
+		activated = append(activated, i)
+		return nil
+	}
+	list.SetShowRecents(2)
+
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+	for _, index := range []int{2, 4, 3} {
+		list.Select(index)
+		list, _ = list.Update(enter)
+	}
+	want := []Item{namedItem("b"), namedItem("d"), namedItem("a"), namedItem("b"), namedItem("c"), namedItem("d")}
+	if got := list.AvailableItems(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Error: expected the 2 most recent items first, got %v", got)
+	}
+	if !list.IsRecent(1) || list.IsRecent(2) {
+		t.Fatalf("Error: expected only the first 2 items to be recent")
+	}
+
+	// Activating items in a clone leaves the original's recents alone.
+	clone := list.Clone()
+	clone.OnActivate = nil
+	clone.Select(3)
+	clone, _ = clone.Update(enter)
+	if fmt.Sprint(list.recents) != "[b d]" {
+		t.Fatalf("Error: expected the clone not to change the recents, got %q", list.recents)
+	}
+
+	list.Select(1)
+	list, _ = list.Update(enter)
+	if list.Index() != 5 || len(activated) != 3 {
+		t.Fatalf("Error: expected the recent item to be selected in its usual place, got index %d", list.Index())
+	}
+
+	list.SetShowRecents(0)
+	if got := len(list.AvailableItems()); got != 4 || list.SelectedItem() != namedItem("d") {
+		t.Fatalf("Error: expected recents to be hidden, got %d items", got)
+	}
+}
+
+func TestItemIndexAtOffset(t *testing.T) {
+	items := make([]Item, 10)
+	for i := range items {
+		items[i] = titledItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, NewDefaultDelegate(), 20, 20)
+	list.Select(9)
+	first, last := list.RenderedRange()
+
+	for _, tc := range []struct {
+		y     int
+		index int
+		ok    bool
+	}{
+		{0, first, true},
+		{1, 0, false},
+		{2, first + 1, true},
+		{-1, 0, false},
+		{(last-first)*2 + 2, 0, false},
+	} {
+		index, ok := list.ItemIndexAtOffset(tc.y)
+		if index != tc.index || ok != tc.ok {
+			t.Errorf("Error: expected (%d, %v) at row %d, got (%d, %v)", tc.index, tc.ok, tc.y, index, ok)
+		}
+	}
+
+	list.SetItems(nil)
+	if _, ok := list.ItemIndexAtOffset(0); ok {
+		t.Fatalf("Error: expected no item without items")
+	}
+}
+
+func TestHover(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, plainDelegate{}, 20, 20)
+	list.SetMouseEnabled(true)
+
+	row := -1
+	for i, line := range strings.Split(list.RenderPlain(), "\n") {
+		if strings.Contains(line, "3. baz") {
+			row = i
+		}
+	}
+
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseMotion, X: 2, Y: row})
+	if !list.IsHovered(2) || list.IsHovered(0) || list.Index() != 0 {
+		t.Fatalf("Error: expected 2 to be hovered without being selected")
+	}
+
+	// Leaving the items, or the list, clears the hover.
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseMotion, X: 2, Y: 0})
+	if list.IsHovered(2) {
+		t.Fatalf("Error: expected hovering the title to clear the hover")
+	}
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseMotion, X: 2, Y: row})
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseMotion, X: 30, Y: row})
+	if list.IsHovered(2) {
+		t.Fatalf("Error: expected leaving the list to clear the hover")
+	}
+}
+
+func TestMouseDisabled(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, plainDelegate{}, 20, 20)
+	var moves []string
+	list.OnReorder = func(from, to int) {
+		moves = append(moves, fmt.Sprintf("%d->%d", from, to))
+	}
+	row := -1
+	for i, line := range strings.Split(list.RenderPlain(), "\n") {
+		if strings.Contains(line, "2. bar") {
+			row = i
+		}
+	}
+
+	// Without enabling the mouse, clicks, motion and drags are ignored.
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseMotion, X: 2, Y: row})
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseLeft, Y: row})
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseLeft, Y: row + 1})
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseRelease, Y: row + 1})
+	if list.Index() != 0 || list.IsHovered(1) || len(moves) != 0 {
+		t.Fatalf("Error: expected mouse events to be ignored, got %d and %v", list.Index(), moves)
+	}
+
+	list.SetMouseEnabled(true)
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseMotion, X: 2, Y: row})
+	if !list.IsHovered(1) {
+		t.Fatalf("Error: expected 1 to be hovered once the mouse is enabled")
+	}
+	list.SetMouseEnabled(false)
+	if list.IsHovered(1) {
+		t.Fatalf("Error: expected disabling the mouse to clear the hover")
+	}
+}
+
+func TestDragToReorder(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz"), namedItem("qux")}, plainDelegate{}, 20, 20)
+	list.SetMouseEnabled(true)
+	var moves []string
+	list.OnReorder = func(from, to int) {
+		moves = append(moves, fmt.Sprintf("%d->%d", from, to))
+	}
+	rowOf := func(s string) int {
+		for i, line := range strings.Split(list.RenderPlain(), "\n") {
+			if strings.Contains(line, s) {
+				return i
+			}
+		}
+		return -1
+	}
+	fooRow, bazRow := rowOf("1. foo"), rowOf("3. baz")
+	height := lipgloss.Height(list.View())
+
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseLeft, Y: fooRow})
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseLeft, Y: bazRow})
+	view := list.View()
+	if !strings.Contains(view, "───") || lipgloss.Height(view) != height {
+		t.Fatalf("Error: expected an indicator while dragging without changing the height, got:\n%s", view)
+	}
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseRelease, Y: bazRow})
+
+	var values []string
+	for _, it := range list.Items() {
+		values = append(values, it.FilterValue())
+	}
+	if fmt.Sprint(values) != "[bar baz foo qux]" || list.Index() != 2 || fmt.Sprint(moves) != "[0->2]" {
+		t.Fatalf("Error: expected foo to be moved to 2 and selected, got %v, %d and %v", values, list.Index(), moves)
+	}
+	if strings.Contains(list.View(), "───") {
+		t.Fatalf("Error: expected no indicator after dropping")
+	}
+
+	// Moving the mouse without a button held ends a drag whose release was
+	// missed, so hovering doesn't move the item or swallow the next click.
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseLeft, Y: fooRow})
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseMotion, X: 2, Y: bazRow})
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseLeft, Y: bazRow})
+	if list.Index() != 2 || len(moves) != 1 {
+		t.Fatalf("Error: expected the click after hovering to select 2 without moving, got %d and %v", list.Index(), moves)
+	}
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseRelease, Y: bazRow})
+
+	// Items can't be dragged while filtered.
+	list, _ = list.Update(list.ApplyFilter("a")())
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseLeft, Y: rowOf("1. bar")})
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseLeft, Y: rowOf("2. baz")})
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseRelease})
+	if len(moves) != 1 {
+		t.Fatalf("Error: expected no move while filtered, got %v", moves)
+	}
+}
+
+func TestDragWithOrigin(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar"), namedItem("baz")}, plainDelegate{}, 20, 20)
+	list.SetMouseEnabled(true)
+	rowOf := func(s string) int {
+		for i, line := range strings.Split(list.RenderPlain(), "\n") {
+			if strings.Contains(line, s) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// The list is drawn 3 columns in and 5 rows down, below other content.
+	list.SetOrigin(3, 5)
+	fooRow, bazRow := rowOf("1. foo")+5, rowOf("3. baz")+5
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseMotion, X: 5, Y: bazRow})
+	if !list.IsHovered(2) {
+		t.Fatalf("Error: expected 2 to be hovered")
+	}
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseLeft, X: 5, Y: fooRow})
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseLeft, X: 5, Y: bazRow})
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseRelease, X: 5, Y: bazRow})
+
+	var values []string
+	for _, it := range list.Items() {
+		values = append(values, it.FilterValue())
+	}
+	if fmt.Sprint(values) != "[bar baz foo]" || list.Index() != 2 {
+		t.Fatalf("Error: expected foo to be moved to 2, got %v and %d", values, list.Index())
+	}
+
+	// Clicks above the list don't land on the items.
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseLeft, X: 5, Y: fooRow - 5})
+	if list.Index() != 2 {
+		t.Fatalf("Error: expected a click above the list to be ignored, got %d", list.Index())
+	}
+}
+
+func TestIsItemVisible(t *testing.T) {
+	items := make([]Item, 20)
+	for i := range items {
+		items[i] = namedItem(fmt.Sprintf("item %d", i))
+	}
+	list := New(items, plainDelegate{}, 20, 10)
+	list.Select(19)
+	first, last := list.RenderedRange()
+
+	for _, index := range []int{first - 1, first, last, last + 1, 20} {
+		want := index >= first && index <= last
+		if got := list.IsItemVisible(index); got != want {
+			t.Errorf("Error: expected %d to be visible: %v, got %v", index, want, got)
+		}
+	}
+
+	list.SetSize(20, 1)
+	if list.IsItemVisible(19) {
+		t.Fatalf("Error: expected no visible items when overflowing")
+	}
+}
+
+func TestTitlePosition(t *testing.T) {
+	list := New([]Item{namedItem("foo"), namedItem("bar")}, plainDelegate{}, 20, 12)
+	list.Title = "Things"
+	list.SetShowHelp(false)
+	list.SetShowStatusBar(false)
+
+	order := func() (title, items int) {
+		lines := strings.Split(list.RenderPlain(), "\n")
+		for i, line := range lines {
+			switch {
+			case strings.Contains(line, "Things"), strings.Contains(line, "Filter:"):
+				title = i
+			case strings.Contains(line, "1. foo"):
+				items = i
+			}
+		}
+		return title, items
+	}
+
+	if title, items := order(); title > items {
+		t.Fatalf("Error: expected the title above the items, got rows %d and %d", title, items)
+	}
+
+	list.SetTitlePosition(TitleBottom)
+	height := lipgloss.Height(list.View())
+	if title, items := order(); title < items {
+		t.Fatalf("Error: expected the title below the items, got rows %d and %d", title, items)
+	}
+	if height != 12 {
+		t.Fatalf("Error: expected the height to stay 12, got %d", height)
+	}
+
+	// Filtering still works from the bottom, and clicks land on the items.
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	list, _ = list.Update(filterItems(list)())
+	list, _ = list.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if list.FilterValue() != "b" || len(list.AvailableItems()) != 1 {
+		t.Fatalf("Error: expected to filter from the bottom, got %q", list.FilterValue())
+	}
+	list.ResetFilter()
+	list.SetMouseEnabled(true)
+	list, _ = list.Update(tea.MouseMsg{Type: tea.MouseLeft, Y: 1})
+	if list.Index() != 1 {
+		t.Fatalf("Error: expected a click on the second row to select 1, got %d", list.Index())
+	}
+}
+
+func TestSplitView(t *testing.T) {
+	list := New([]Item{titledItem("a rather long item title"), titledItem("short")}, NewDefaultDelegate(), 40, 10)
+	list.SetShowHelp(false)
+
+	view := list.SplitView("detail pane", 0.5)
+	lines := strings.Split(stripANSI(view), "\n")
+	if len(lines) != 10 {
+		t.Fatalf("Error: expected 10 lines, got %d:\n%s", len(lines), view)
+	}
+	for _, line := range lines {
+		if w := lipgloss.Width(line); w != 40 {
+			t.Fatalf("Error: expected every line to be 40 wide, got %d: %q", w, line)
+		}
+	}
+	if i := strings.Index(lines[0], "detail pane"); i != 20 {
+		t.Fatalf("Error: expected the detail at column 20, got %d: %q", i, lines[0])
+	}
+	if strings.Contains(view, "a rather long item title") {
+		t.Fatalf("Error: expected the title to be truncated to the list's half")
+	}
+	if list.Width() != 40 {
+		t.Fatalf("Error: expected the list's width to be unchanged, got %d", list.Width())
+	}
+}
+
+type keywordItem struct {
+	name     string
+	keywords []string
+}
+
+func (k keywordItem) FilterValue() string      { return k.name }
+func (k keywordItem) FilterKeywords() []string { return k.keywords }
+
+func TestFilterKeywords(t *testing.T) {
+	list := New([]Item{
+		keywordItem{"Settings", []string{"preferences", "options"}},
+		keywordItem{"Profile", []string{"account"}},
+		namedItem("Options menu"),
+	}, plainDelegate{}, 20, 20)
+
+	// The plain item matches on its name first, then the keyword item.
+	list, _ = list.Update(list.ApplyFilter("options")())
+	var names []string
+	for _, it := range list.AvailableItems() {
+		names = append(names, it.FilterValue())
+	}
+	if fmt.Sprint(names) != "[Options menu Settings]" {
+		t.Fatalf("Error: expected the keyword match after the name match, got %v", names)
+	}
+	if matches := list.MatchesForItem(1); len(matches) != 0 {
+		t.Fatalf("Error: expected no highlights for a keyword match, got %v", matches)
+	}
+	if matches := list.MatchesForItem(0); len(matches) == 0 {
+		t.Fatalf("Error: expected highlights for a name match")
+	}
+
+	// Cached items keep their keywords.
+	list.SetItems(WrapCached(list.Items()))
+	list, _ = list.Update(list.ApplyFilter("account")())
+	if len(list.AvailableItems()) != 1 || list.AvailableItems()[0].FilterValue() != "Profile" {
+		t.Fatalf("Error: expected only Profile to match its keyword, got %v", list.AvailableItems())
+	}
+}
+
+func TestFilterInputScrolls(t *testing.T) {
+	for _, width := range []int{30, 12} {
+		list := New([]Item{namedItem("foo")}, plainDelegate{}, width, 10)
+		list.SetFilterCharLimit(200)
+		list.SetShowFilterCharCount(true)
+		list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+		term := strings.Repeat("abcdefghij", 10)
+		list, _ = list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(term)})
+
+		if list.FilterValue() != term {
+			t.Fatalf("Error: expected the whole term past the old limit, got %d characters", len(list.FilterValue()))
+		}
+		for _, line := range strings.Split(list.View(), "\n") {
+			if w := lipgloss.Width(line); w > width {
+				t.Fatalf("Error: expected lines at most %d wide, got %d: %q", width, w, stripANSI(line))
+			}
+		}
+		if width >= 30 && !strings.Contains(list.RenderPlain(), "hij") {
+			t.Fatalf("Error: expected the end of the term to be scrolled into view at width %d", width)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 const (
 	bullet   = "•"
 	ellipsis = "…"
+	pinGlyph = "★"
 )
 
 // Styles contains style definitions for this list component. By default, these
@@ -15,10 +16,22 @@ const (
 type Styles struct {
 	TitleBar     lipgloss.Style
 	Title        lipgloss.Style
+	TitleBadge   lipgloss.Style
 	Spinner      lipgloss.Style
 	FilterPrompt lipgloss.Style
 	FilterCursor lipgloss.Style
 
+	// Character count shown next to the filter input, and its style once the
+	// character limit has been reached.
+	FilterCharCount      lipgloss.Style
+	FilterCharCountLimit lipgloss.Style
+
+	// Number of items matching the filter, shown next to the filter input.
+	FilterMatchCount lipgloss.Style
+
+	// Completion of the filter shown after the filter input.
+	FilterCompletion lipgloss.Style
+
 	// Default styling for matched characters in a filter. This can be
 	// overridden by delegates.
 	DefaultFilterCharacterMatch lipgloss.Style
@@ -28,10 +41,16 @@ type Styles struct {
 	StatusBarActiveFilter lipgloss.Style
 	StatusBarFilterCount  lipgloss.Style
 
+	Header  lipgloss.Style
+	Footer  lipgloss.Style
 	NoItems lipgloss.Style
 
 	HelpStyle lipgloss.Style
 
+	// The line showing where an item being dragged with the mouse would be
+	// moved to.
+	DragIndicator lipgloss.Style
+
 	// Styled characters.
 	DividerDot lipgloss.Style
 }
@@ -52,6 +71,12 @@ func DefaultStyles() (s Styles) {
 		Foreground(lipgloss.Color("230")).
 		Padding(0, 1)
 
+	s.TitleBadge = lipgloss.NewStyle().
+		Background(lipgloss.AdaptiveColor{Light: "#F793FF", Dark: "#AD58B4"}).
+		Foreground(lipgloss.Color("230")).
+		Padding(0, 1).
+		MarginLeft(1)
+
 	s.Spinner = lipgloss.NewStyle().
 		Foreground(lipgloss.AdaptiveColor{Light: "#8E8E8E", Dark: "#747373"})
 
@@ -61,6 +86,15 @@ func DefaultStyles() (s Styles) {
 	s.FilterCursor = lipgloss.NewStyle().
 		Foreground(lipgloss.AdaptiveColor{Light: "#04B575", Dark: "#ECFD65"})
 
+	s.FilterCharCount = lipgloss.NewStyle().Foreground(subduedColor)
+
+	s.FilterCharCountLimit = lipgloss.NewStyle().
+		Foreground(lipgloss.AdaptiveColor{Light: "#FF4672", Dark: "#ED567A"})
+
+	s.FilterMatchCount = lipgloss.NewStyle().Foreground(subduedColor)
+
+	s.FilterCompletion = lipgloss.NewStyle().Foreground(subduedColor)
+
 	s.DefaultFilterCharacterMatch = lipgloss.NewStyle().Underline(true)
 
 	s.StatusBar = lipgloss.NewStyle().
@@ -74,11 +108,22 @@ func DefaultStyles() (s Styles) {
 
 	s.StatusBarFilterCount = lipgloss.NewStyle().Foreground(verySubduedColor)
 
+	s.Header = lipgloss.NewStyle().
+		Foreground(subduedColor).
+		Padding(0, 0, 0, 2)
+
+	s.Footer = lipgloss.NewStyle().
+		Foreground(subduedColor).
+		Padding(1, 0, 0, 2)
+
 	s.NoItems = lipgloss.NewStyle().
 		Foreground(lipgloss.AdaptiveColor{Light: "#909090", Dark: "#626262"})
 
 	s.HelpStyle = lipgloss.NewStyle().Padding(1, 0, 0, 2)
 
+	s.DragIndicator = lipgloss.NewStyle().
+		Foreground(lipgloss.AdaptiveColor{Light: "#F793FF", Dark: "#AD58B4"})
+
 	s.DividerDot = lipgloss.NewStyle().
 		Foreground(verySubduedColor).
 		SetString(" " + bullet + " ")